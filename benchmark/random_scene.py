@@ -0,0 +1,41 @@
+# Python script that writes a configuration space with many random obstacles,
+# used to benchmark obstacle queries on large scenes.
+#
+# Example usage:
+#     python3 benchmark/random_scene.py 5000 > benchmark/large_input.txt
+#
+# The Go benchmarks of obstacle queries build their scenes the same way:
+#     go test -bench Feasible ./config
+
+
+import random
+import sys
+
+# Scene parameters, matching sample_input.txt
+height, width = 4000, 8000
+start, goal = (100, 100), (7900, 3900)
+count = int(sys.argv[1]) if len(sys.argv) > 1 else 1000
+random.seed(int(sys.argv[2]) if len(sys.argv) > 2 else 0)
+
+print(f"window,{height},{width}")
+print("radius,15")
+print("delta,10")
+print(f"start,{start[0]},{start[1]}")
+print(f"goal,{goal[0]},{goal[1]}")
+
+# Keep obstacles away from the start and goal points
+def clear(x, y, size):
+    return all(abs(x - px) > size + 50 or abs(y - py) > size + 50
+               for px, py in (start, goal))
+
+placed = 0
+while placed < count:
+    size = random.uniform(5, 60)
+    x, y = random.uniform(0, width), random.uniform(0, height)
+    if not clear(x, y, size):
+        continue
+    if random.random() < 0.5:
+        print(f"circle,{x:.1f},{y:.1f},{size:.1f}")
+    else:
+        print(f"rectangle,{x:.1f},{y:.1f},{size:.1f},{random.uniform(5, 60):.1f}")
+    placed += 1
@@ -10,6 +10,7 @@ import (
 	"math"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 )
 
 // NewWorkBalancingExecutor returns an ExecutorService that is implemented using
@@ -19,71 +20,93 @@ import (
 // grab from the executor in one time period.
 // @param thresholdBalance - The threshold used to know when to perform
 // balancing.
+// @param opts - Options of the executor, such as its panic policy or deque.
 func NewWorkBalancingExecutor(capacity, thresholdQueue, thresholdBalance int,
+	opts ...Option,
 ) ExecutorService {
-	var workers []Worker
-	for i := 0; i < capacity; i++ {
-		workers = append(workers, Worker{workerQueue: NewUnBoundedDEQueue()})
-	}
 	executor := &Executor{
-		workers:          workers,
 		globalQueue:      NewUnBoundedDEQueue(),
-		shutdown:         make(chan interface{}),
+		shutdown:         make(chan struct{}),
 		thresholdBalance: thresholdBalance,
 		thresholdQueue:   thresholdQueue,
 		wg:               sync.WaitGroup{},
 	}
-	for worker := 0; worker < capacity; worker++ {
-		go executor.runWorkBalancer(worker)
+	for _, opt := range opts {
+		opt(executor)
 	}
+	executor.start(capacity, executor.runWorkBalancer)
 	return executor
 }
 
 // runWorkBalancer is the main worker instructions for the worker balancing routine
-func (e *Executor) runWorkBalancer(me int) {
+func (e *Executor) runWorkBalancer(me *Worker) {
+	var idle idler
 	for {
 		select {
 		case <-e.shutdown:
 			return
+		case <-me.retire:
+			return
 		default:
 			// Attempt to pop and call a task from local queue
-			e.popCallTask(me)
+			successfulCall := e.popCallTask(me)
 
 			// Balance queues if needed
 			if e.globalQueue.IsEmpty() {
-				size := e.workers[me].workerQueue.Size()
+				size := me.workerQueue.Size()
 				// Randomly select victim
 				if rand.Intn(int(size+1)) == int(size) {
-					victim := rand.Intn(len(e.workers))
-					diff := math.Abs(float64(e.workers[victim].workerQueue.Size() -
-						e.workers[me].workerQueue.Size()))
+					e.poolLock.RLock()
+					victim := e.workers[rand.Intn(len(e.workers))]
+					diff := math.Abs(float64(victim.workerQueue.Size() -
+						me.workerQueue.Size()))
 					// If difference is greater than threshold, balance
 					if diff >= float64(e.thresholdBalance) {
 						e.balance(victim, me)
 					}
+					e.poolLock.RUnlock()
 				}
 			}
+
+			// Park after spinning for a while without work
+			if successfulCall {
+				e.busy(me, &idle)
+			} else {
+				e.idle(me, &idle)
+			}
 		}
 	}
 }
 
-// Balances the local queues of two workers
-func (e *Executor) balance(victim, me int) {
-	if e.workers[victim].workerQueue.Size() > e.workers[me].workerQueue.Size() {
+// Balances the local queues of two workers, the caller holds the pool lock
+func (e *Executor) balance(victim, me *Worker) {
+	atomic.AddInt64(&me.balances, 1)
+	if victim.workerQueue.Size() > me.workerQueue.Size() {
 		// If victim queue is larger, grab from victim and place into me
 		for i := 0; i < e.thresholdBalance/2; i++ {
-			task := e.workers[victim].workerQueue.PopTop()
+			task := victim.workerQueue.PopTop()
 			if task != nil {
-				e.workers[me].workerQueue.PushBottom(task)
+				me.workerQueue.PushBottom(task)
 			}
 		}
 	} else {
-		// If victim queue is smaller, grab from me and place into victim
+		// If victim queue is smaller, grab from me and place into victim. A
+		// victim deque that only its owner may push to gets the tasks through
+		// the global queue.
+		var dest DEQueue = victim.workerQueue
+		if !sharedPush(dest) {
+			// A worker may not wait for space in a bounded global queue
+			if e.bounded != nil {
+				return
+			}
+			dest = e.globalQueue
+		}
 		for i := 0; i < e.thresholdBalance/2; i++ {
-			task := e.workers[me].workerQueue.PopTop()
+			task := me.workerQueue.PopTop()
 			if task != nil {
-				e.workers[victim].workerQueue.PushBottom(task)
+				dest.PushBottom(task)
 			}
 		}
+		e.signal()
 	}
 }
@@ -0,0 +1,125 @@
+//
+// boundeddeque.go
+// Christian Jordan
+// Bounded double ended queue with blocking push
+//
+
+package concurrent
+
+import (
+	"sync"
+)
+
+// BoundedDEQueue is a fixed capacity ring buffer deque. PushBottom blocks
+// while the deque is full, TryPushBottom fails instead.
+type BoundedDEQueue struct {
+	tasks []Task        // Ring buffer of tasks
+	head  int           // Index of the top task
+	count int32         // Number of tasks
+	free  chan struct{} // One token per free slot
+	lock  sync.Mutex    // Lock for the ring buffer
+}
+
+// NewBoundedDEQueue returns an empty BoundedDEQueue holding at most capacity
+// tasks
+func NewBoundedDEQueue(capacity int) *BoundedDEQueue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	q := &BoundedDEQueue{
+		tasks: make([]Task, capacity),
+		free:  make(chan struct{}, capacity),
+	}
+	for i := 0; i < capacity; i++ {
+		q.free <- struct{}{}
+	}
+	return q
+}
+
+// PushBottom adds a task to the bottom of the deque, waiting for space
+func (q *BoundedDEQueue) PushBottom(task Task) {
+	<-q.free
+	q.push(task)
+}
+
+// TryPushBottom adds a task to the bottom of the deque if it is not full.
+// Returns false if the deque is full.
+func (q *BoundedDEQueue) TryPushBottom(task Task) bool {
+	select {
+	case <-q.free:
+		q.push(task)
+		return true
+	default:
+		return false
+	}
+}
+
+// PushBottomWait adds a task to the bottom of the deque, waiting for space
+// until stop or cancel is closed. Returns false if the task was not added.
+func (q *BoundedDEQueue) PushBottomWait(task Task,
+	stop <-chan struct{},
+	cancel <-chan struct{},
+) bool {
+	select {
+	case <-q.free:
+		q.push(task)
+		return true
+	case <-stop:
+		return false
+	case <-cancel:
+		return false
+	}
+}
+
+// Add a task to the bottom of the ring buffer, a slot has been reserved
+func (q *BoundedDEQueue) push(task Task) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.tasks[(q.head+int(q.count))%len(q.tasks)] = task
+	q.count++
+}
+
+// PopTop removes the top task, nil if the deque is empty
+func (q *BoundedDEQueue) PopTop() Task {
+	q.lock.Lock()
+	if q.count == 0 {
+		q.lock.Unlock()
+		return nil
+	}
+	task := q.tasks[q.head]
+	q.tasks[q.head] = nil
+	q.head = (q.head + 1) % len(q.tasks)
+	q.count--
+	q.lock.Unlock()
+	q.free <- struct{}{}
+	return task
+}
+
+// PopBottom removes the bottom task, nil if the deque is empty
+func (q *BoundedDEQueue) PopBottom() Task {
+	q.lock.Lock()
+	if q.count == 0 {
+		q.lock.Unlock()
+		return nil
+	}
+	q.count--
+	i := (q.head + int(q.count)) % len(q.tasks)
+	task := q.tasks[i]
+	q.tasks[i] = nil
+	q.lock.Unlock()
+	q.free <- struct{}{}
+	return task
+}
+
+// Size returns the number of tasks in the deque
+func (q *BoundedDEQueue) Size() int32 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.count
+}
+
+// IsEmpty returns whether the deque is empty
+func (q *BoundedDEQueue) IsEmpty() bool { return q.Size() == 0 }
+
+// Capacity returns the number of tasks the deque can hold
+func (q *BoundedDEQueue) Capacity() int { return len(q.tasks) }
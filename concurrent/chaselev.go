@@ -0,0 +1,167 @@
+//
+// chaselev.go
+// Christian Jordan
+// Chase-Lev work-stealing deque
+//
+
+package concurrent
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// ChaseLevDeque is a growable circular array deque (Chase and Lev, "Dynamic
+// Circular Work-Stealing Deque", 2005). Only the owning worker may call
+// PushBottom and PopBottom, which use no CAS unless the deque holds a single
+// task. Any goroutine may call PopTop to steal. The slots hold the
+// *TaskFuture tasks the executors push, so that a push does not allocate.
+// Other tasks are boxed in a future of their own.
+type ChaseLevDeque struct {
+	top    int64          // Index of the oldest task, advanced by thieves with CAS
+	bottom int64          // Index after the newest task, written by the owner only
+	array  unsafe.Pointer // Current *circularArray
+}
+
+// circularArray is a power of two sized ring buffer of task slots
+type circularArray struct {
+	slots []unsafe.Pointer // Each slot holds a *TaskFuture
+	mask  int64            // Size of the array minus one
+}
+
+// Initial number of slots of a ChaseLevDeque
+const chaseLevSize = 64
+
+// NewChaseLevDeque returns an empty ChaseLevDeque
+func NewChaseLevDeque() *ChaseLevDeque {
+	return &ChaseLevDeque{array: unsafe.Pointer(newCircularArray(chaseLevSize))}
+}
+
+// Create a new circularArray with a power of two size
+func newCircularArray(size int64) *circularArray {
+	return &circularArray{slots: make([]unsafe.Pointer, size), mask: size - 1}
+}
+
+// Get the task at an index
+func (a *circularArray) get(i int64) *TaskFuture {
+	return (*TaskFuture)(atomic.LoadPointer(&a.slots[i&a.mask]))
+}
+
+// Put a task at an index
+func (a *circularArray) put(i int64, task *TaskFuture) {
+	atomic.StorePointer(&a.slots[i&a.mask], unsafe.Pointer(task))
+}
+
+// boxedTask is a task other than a *TaskFuture pushed to a ChaseLevDeque
+type boxedTask struct {
+	task Task
+}
+
+// Get the slot of a task, boxing tasks that are not futures
+func box(task Task) *TaskFuture {
+	if f, ok := task.(*TaskFuture); ok {
+		return f
+	}
+	return &TaskFuture{task: boxedTask{task}}
+}
+
+// Get the task held by a slot
+func unbox(f *TaskFuture) Task {
+	if f == nil {
+		return nil
+	}
+	if b, ok := f.task.(boxedTask); ok {
+		return b.task
+	}
+	return f
+}
+
+// Copy the tasks between top and bottom into an array twice as large
+func (a *circularArray) grow(top, bottom int64) *circularArray {
+	grown := newCircularArray(2 * (a.mask + 1))
+	for i := top; i < bottom; i++ {
+		grown.put(i, a.get(i))
+	}
+	return grown
+}
+
+// PushBottom adds a task to the bottom of the deque. Owner only.
+func (q *ChaseLevDeque) PushBottom(task Task) {
+	bottom := atomic.LoadInt64(&q.bottom)
+	top := atomic.LoadInt64(&q.top)
+	array := (*circularArray)(atomic.LoadPointer(&q.array))
+	if bottom-top > array.mask {
+		array = array.grow(top, bottom)
+		atomic.StorePointer(&q.array, unsafe.Pointer(array))
+	}
+	array.put(bottom, box(task))
+	atomic.StoreInt64(&q.bottom, bottom+1)
+}
+
+// PopBottom removes the newest task, nil if the deque is empty or the last
+// task was stolen. Owner only.
+func (q *ChaseLevDeque) PopBottom() Task {
+	bottom := atomic.LoadInt64(&q.bottom) - 1
+	array := (*circularArray)(atomic.LoadPointer(&q.array))
+	atomic.StoreInt64(&q.bottom, bottom)
+	top := atomic.LoadInt64(&q.top)
+	if top > bottom {
+		// Empty, restore bottom
+		atomic.StoreInt64(&q.bottom, bottom+1)
+		return nil
+	}
+
+	task := array.get(bottom)
+	if top == bottom {
+		// Last task, race the thieves for it
+		won := atomic.CompareAndSwapInt64(&q.top, top, top+1)
+		atomic.StoreInt64(&q.bottom, bottom+1)
+		if !won {
+			return nil
+		}
+	}
+	return unbox(task)
+}
+
+// PopTop steals the oldest task, nil if the deque is empty or another thief
+// or the owner took it first
+func (q *ChaseLevDeque) PopTop() Task {
+	top := atomic.LoadInt64(&q.top)
+	bottom := atomic.LoadInt64(&q.bottom)
+	if top >= bottom {
+		return nil
+	}
+	array := (*circularArray)(atomic.LoadPointer(&q.array))
+	task := array.get(top)
+	if !atomic.CompareAndSwapInt64(&q.top, top, top+1) {
+		return nil
+	}
+	return unbox(task)
+}
+
+// Size returns the number of tasks in the deque
+func (q *ChaseLevDeque) Size() int32 {
+	size := atomic.LoadInt64(&q.bottom) - atomic.LoadInt64(&q.top)
+	if size < 0 {
+		return 0
+	}
+	return int32(size)
+}
+
+// IsEmpty returns whether the deque is empty
+func (q *ChaseLevDeque) IsEmpty() bool { return q.Size() == 0 }
+
+// ownerPush marks the deque as accepting pushes from its owner only
+func (q *ChaseLevDeque) ownerPush() {}
+
+// singleOwner is implemented by deques whose bottom end may only be used by
+// the worker owning them
+type singleOwner interface {
+	ownerPush()
+}
+
+// Checks if a deque accepts pushes from other goroutines than its owner
+func sharedPush(q DEQueue) bool {
+	_, ok := q.(singleOwner)
+	return !ok
+}
@@ -0,0 +1,162 @@
+//
+// chaselev_test.go
+// Christian Jordan
+// Tests of the Chase-Lev work-stealing deque, and stress tests comparing it
+// with the unbounded deque
+//
+
+package concurrent
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// The deques of the workers
+var deques = []struct {
+	name string
+	new  func() DEQueue
+}{
+	{"unbounded", func() DEQueue { return NewUnBoundedDEQueue() }},
+	{"chaselev", func() DEQueue { return NewChaseLevDeque() }},
+}
+
+// Number of goroutines stealing from the owner in the stress tests
+func thieves() int {
+	if n := runtime.NumCPU() - 1; n > 2 {
+		return n
+	}
+	return 2
+}
+
+// Pushes futures from the owner, which pops some of them back between bursts
+// of 16, while thieves steal from the top. Every future must be taken exactly
+// once.
+func stressDeque(q DEQueue, futures []*TaskFuture) error {
+	index := make(map[*TaskFuture]int, len(futures))
+	for i, f := range futures {
+		index[f] = i
+	}
+	taken := make([]int32, len(futures))
+	remaining := int64(len(futures))
+	take := func(task Task) {
+		atomic.AddInt32(&taken[index[task.(*TaskFuture)]], 1)
+		atomic.AddInt64(&remaining, -1)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < thieves(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt64(&remaining) > 0 {
+				if task := q.PopTop(); task != nil {
+					take(task)
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	// Owner
+	const burst = 16
+	for i, f := range futures {
+		q.PushBottom(f)
+		if i%burst == burst-1 {
+			for j := 0; j < burst/2; j++ {
+				if task := q.PopBottom(); task != nil {
+					take(task)
+				}
+			}
+		}
+	}
+	for atomic.LoadInt64(&remaining) > 0 {
+		if task := q.PopBottom(); task != nil {
+			take(task)
+		}
+	}
+	wg.Wait()
+
+	for i, n := range taken {
+		if n != 1 {
+			return fmt.Errorf("task %d taken %d times", i, n)
+		}
+	}
+	if !q.IsEmpty() {
+		return fmt.Errorf("%d tasks left in the deque", q.Size())
+	}
+	return nil
+}
+
+// Creates futures of empty tasks
+func newFutures(n int) []*TaskFuture {
+	futures := make([]*TaskFuture, n)
+	for i := range futures {
+		futures[i] = NewTaskFuture(RunnableFunc(func() {}))
+	}
+	return futures
+}
+
+// Every pushed task is popped or stolen exactly once
+func TestDequeStress(t *testing.T) {
+	tasks := 200000
+	if testing.Short() {
+		tasks = 20000
+	}
+	for _, deque := range deques {
+		t.Run(deque.name, func(t *testing.T) {
+			for round := 0; round < 3; round++ {
+				if err := stressDeque(deque.new(), newFutures(tasks)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Throughput of the owner and thieves sharing a deque
+func BenchmarkDequeStress(b *testing.B) {
+	for _, deque := range deques {
+		b.Run(deque.name, func(b *testing.B) {
+			futures := newFutures(b.N)
+			b.ResetTimer()
+			if err := stressDeque(deque.new(), futures); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
+
+// Futures are stored without allocating, other tasks are boxed and returned
+// as pushed
+func TestChaseLevDequeTaskTypes(t *testing.T) {
+	q := NewChaseLevDeque()
+	f := NewTaskFuture(RunnableFunc(func() {}))
+	if allocs := testing.AllocsPerRun(100, func() {
+		q.PushBottom(f)
+		q.PopBottom()
+	}); allocs != 0 {
+		t.Fatalf("pushing a future allocates %v times", allocs)
+	}
+
+	task := RunnableFunc(func() {})
+	q.PushBottom(task)
+	q.PushBottom(f)
+	q.PushBottom(nil)
+	if got := q.PopBottom(); got != nil {
+		t.Fatalf("popped %v in place of nil", got)
+	}
+	if got := q.PopBottom(); got != Task(f) {
+		t.Fatalf("popped %v in place of the future", got)
+	}
+	if got, ok := q.PopTop().(RunnableFunc); !ok || got == nil {
+		t.Fatal("pushed task not returned")
+	}
+	if !q.IsEmpty() {
+		t.Fatal("deque not empty")
+	}
+}
@@ -7,9 +7,12 @@
 package concurrent
 
 import (
+	"context"
+	"errors"
 	"math"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // Runnable represents a task that does not return a value.
@@ -30,6 +33,65 @@ type Future interface {
 	// by the Call idthod. If the task associated with the Future is a Runnable
 	// then it must return nil once the task is complete.
 	Get() interface{}
+
+	// GetWithTimeout waits at most timeout for the task to complete. It returns
+	// the value Get would return and true, or nil and false on a timeout.
+	GetWithTimeout(timeout time.Duration) (interface{}, bool)
+
+	// IsDone returns whether the task has completed, without waiting.
+	IsDone() bool
+
+	// Err waits for the task to complete and returns a *PanicError if the task
+	// panicked, ErrCancelled if it was dropped before running, or nil. Get then
+	// returns the same error.
+	Err() error
+}
+
+// PanicPolicy decides what an executor does after a task panics
+type PanicPolicy int
+
+const (
+	// ContinueOnPanic keeps running the other tasks
+	ContinueOnPanic PanicPolicy = iota
+	// FailFast cancels every task that has not started running yet
+	FailFast
+)
+
+// Option configures an executor
+type Option func(*Executor)
+
+// WithPanicPolicy sets what the executor does after a task panics
+func WithPanicPolicy(policy PanicPolicy) Option {
+	return func(e *Executor) { e.panicPolicy = policy }
+}
+
+// FullPolicy decides what Submit does when a bounded global queue is full
+type FullPolicy int
+
+const (
+	// BlockWhenFull waits for space in the queue
+	BlockWhenFull FullPolicy = iota
+	// FailWhenFull cancels the task with ErrQueueFull
+	FailWhenFull
+)
+
+// ErrQueueFull is the error of a task submitted while the queue was full
+var ErrQueueFull = errors.New("queue full")
+
+// WithBoundedQueue bounds the global queue to a capacity, so that producers
+// submitting more tasks than the workers keep up with are throttled
+func WithBoundedQueue(capacity int, policy FullPolicy) Option {
+	return func(e *Executor) {
+		e.bounded = NewBoundedDEQueue(capacity)
+		e.globalQueue = e.bounded
+		e.fullPolicy = policy
+	}
+}
+
+// WithDeque sets the constructor of the worker deques, UnBoundedDEQueue by
+// default
+func WithDeque(newDeque func() DEQueue) Option {
+	return func(e *Executor) { e.newDeque = newDeque }
 }
 
 // ExecutorService represents a service that can run om Runnable and/or Callable
@@ -37,8 +99,28 @@ type Future interface {
 type ExecutorService interface {
 
 	// Submits a task for execution and returns a Future representing that task.
+	// A task that is both is called as a Callable. Tasks that are neither a
+	// Runnable nor a Callable are ignored and nil is returned.
 	Submit(task interface{}) Future
 
+	// SubmitCtx submits a task that is dropped, with the context's error as the
+	// error of its Future, if the context is done before the task starts.
+	SubmitCtx(ctx context.Context, task interface{}) Future
+
+	// Panics returns the number of tasks that panicked.
+	Panics() int64
+
+	// IdleStats returns the time the workers spent spinning and parked.
+	IdleStats() IdleStats
+
+	// Stats returns a snapshot of the telemetry of every worker.
+	Stats() Stats
+
+	// Resize grows or shrinks the pool to n workers. Retired workers finish
+	// their running task and hand their queued tasks back to the pool before
+	// Resize returns.
+	Resize(n int) error
+
 	// Shutdown initiates a shutdown of the service. It is unsafe to call Shutdown
 	// at the said tiid as the Submit idthod. All tasks must be submitted before
 	// calling Shutdown. All Submit calls during and after the call to the Shutdown
@@ -46,68 +128,232 @@ type ExecutorService interface {
 	// the service is completely shutdown (i.e., no more pending tasks and all
 	// goroutines spawned by the service are terminated).
 	Shutdown()
+
+	// ShutdownNow stops the service without running the pending tasks. It waits
+	// for the tasks already running to complete, cancels the Futures of the
+	// pending tasks and returns those tasks. Later Submit calls are cancelled.
+	ShutdownNow() []interface{}
+
+	// AwaitTermination waits at most timeout for the goroutines spawned by the
+	// service to terminate after a shutdown. Returns false on a timeout.
+	AwaitTermination(timeout time.Duration) bool
 }
 
 // Executor service
 type Executor struct {
-	workers          []Worker          // The workers in the pool
-	wg               sync.WaitGroup    // WaitGroup for workers
-	globalQueue      *UnBoundedDEQueue // The global queue
-	thresholdBalance int               // Threshold for balancing
-	thresholdQueue   int               // Threshold for grabbing from queue
-	shutdown         chan interface{}  // Channel for shutdown
+	workers          []*Worker        // The workers in the pool, copied on a resize
+	poolLock         sync.RWMutex     // Keeps workers from changing under a victim
+	retired          []*Worker        // Workers retired by a resize
+	run              func(w *Worker)  // Main instructions of a worker
+	wg               sync.WaitGroup   // WaitGroup for workers
+	globalQueue      DEQueue          // The global queue
+	thresholdBalance int              // Threshold for balancing
+	thresholdQueue   int              // Threshold for grabbing from queue
+	shutdown         chan struct{}    // Channel for shutdown
+	panicPolicy      PanicPolicy      // What to do after a task panics
+	panics           int64            // Number of tasks that panicked
+	failed           int32            // Set once a panic cancels the other tasks
+	ctx              context.Context  // Context bounding the executor, nil if unbound
+	running          sync.WaitGroup   // WaitGroup for the worker goroutines
+	terminated       chan interface{} // Closed once every worker has returned
+	stopOnce         sync.Once        // Closes the shutdown channel once
+	submitLock       sync.RWMutex     // Keeps Submit from racing ShutdownNow
+	stopped          int32            // Set once ShutdownNow was called
+	wake             chan struct{}    // Signals parked workers that work was pushed
+	newDeque         func() DEQueue   // Creates the deque of a worker
+	bounded          *BoundedDEQueue  // The global queue if it is bounded
+	fullPolicy       FullPolicy       // What Submit does when bounded is full
+	steal            StealOptions     // Steal policy and victim selection
+	watches          ctxWatches       // Futures cancelled by their context
 }
 
 // Worker struct
 type Worker struct {
+	id          int // Index of the worker in the pool
 	workerQueue DEQueue
+	retire      chan struct{} // Closed to retire the worker
+	done        chan struct{} // Closed once the worker has returned
 	tst         int64
+	spinTime    int64 // Nanoseconds spent spinning without work
+	parkTime    int64 // Nanoseconds spent parked
+	parks       int64 // Number of times the worker parked
+	workerCounters
 }
 
 // Attempts to run a task from the worker queue. If the worker queue is empty,
-// it will attempt to grab from the global queue
-func (e *Executor) popCallTask(id int) bool {
-	if !e.workers[id].workerQueue.IsEmpty() {
+// it will attempt to grab from the global queue. Returns true if a task was
+// run or grabbed.
+func (e *Executor) popCallTask(w *Worker) bool {
+	if depth := w.workerQueue.Size(); depth > 0 {
 		// Task is nil if pop unsuccessful
-		task := e.workers[id].workerQueue.PopBottom()
+		task := w.workerQueue.PopBottom()
 		if task != nil {
-			task.(Runnable).Run()
+			w.sampleDepth(depth)
+			e.runTask(task.(*TaskFuture))
 			e.wg.Done()
-			atomic.AddInt64(&e.workers[id].tst, 1)
+			atomic.AddInt64(&w.tst, 1)
 			return true
 		}
 	} else if !e.globalQueue.IsEmpty() {
 		// Grab from global queue
-		grab := math.Max(float64(e.globalQueue.Size())/float64(len(e.workers)),
-			float64(len(e.workers)))
+		workers := len(e.pool())
+		grab := math.Max(float64(e.globalQueue.Size())/float64(workers),
+			float64(workers))
+		grabbed := false
 		for i := 0; i < int(grab); i++ {
 			if e.globalQueue.IsEmpty() {
 				break
 			}
 			task := e.globalQueue.PopTop()
 			if task != nil {
-				e.workers[id].workerQueue.PushBottom(task)
+				w.workerQueue.PushBottom(task)
+				atomic.AddInt64(&w.grabbed, 1)
+				grabbed = true
 			}
 		}
-		return false
+		if grabbed {
+			w.sampleDepth(w.workerQueue.Size())
+		}
+		return grabbed
 	}
 	return false
 }
 
+// Runs a task unless a panic has cancelled the remaining tasks or the
+// context of the task is done. A future cancelled while queued is skipped.
+func (e *Executor) runTask(f *TaskFuture) {
+	if atomic.LoadInt32(&e.failed) == 1 {
+		f.cancel(ErrCancelled)
+		return
+	}
+	if f.ctx.Err() != nil {
+		f.cancel(f.ctx.Err())
+		return
+	}
+	if !f.claim() {
+		return
+	}
+	if _, ok := f.run().(*PanicError); ok {
+		atomic.AddInt64(&e.panics, 1)
+		if e.panicPolicy == FailFast {
+			atomic.StoreInt32(&e.failed, 1)
+		}
+	}
+}
+
 // Submits a task to the executor
 func (e *Executor) Submit(task interface{}) Future {
-	// Do not let nil tasks be submitted
-	if task == nil {
+	return e.SubmitCtx(context.Background(), task)
+}
+
+// Submits a task that is dropped if a context is done before it starts
+func (e *Executor) SubmitCtx(ctx context.Context, task interface{}) Future {
+	// Only Runnable and Callable tasks can be submitted
+	if !IsTask(task) {
 		return nil
 	}
-	e.wg.Add(1)
 	f := NewTaskFuture(task)
-	e.globalQueue.PushBottom(task)
+	f.ctx = ctx
+	if ctx.Err() != nil {
+		f.cancel(ctx.Err())
+		return f
+	}
+
+	e.submitLock.RLock()
+	defer e.submitLock.RUnlock()
+	if atomic.LoadInt32(&e.failed) == 1 || atomic.LoadInt32(&e.stopped) == 1 {
+		f.cancel(ErrCancelled)
+		return f
+	}
+	e.wg.Add(1)
+	if !e.push(f) {
+		e.wg.Done()
+		return f
+	}
+	e.signal()
+	// Complete the future as soon as its context is done, the worker that
+	// pops it later skips it
+	if ctx.Done() != nil {
+		e.watchFuture(f)
+	}
 	return f
 }
 
+// Pushes a future to the global queue. A bounded queue that is full is waited
+// on or fails the push, according to the full policy. A future that is not
+// pushed is cancelled.
+func (e *Executor) push(f *TaskFuture) bool {
+	if e.bounded == nil {
+		e.globalQueue.PushBottom(f)
+		return true
+	}
+	if e.fullPolicy == FailWhenFull {
+		if e.bounded.TryPushBottom(f) {
+			return true
+		}
+		f.cancel(ErrQueueFull)
+		return false
+	}
+	if e.bounded.PushBottomWait(f, e.shutdown, f.ctx.Done()) {
+		return true
+	}
+	if f.ctx.Err() != nil {
+		f.cancel(f.ctx.Err())
+	} else {
+		f.cancel(ErrCancelled)
+	}
+	return false
+}
+
+// Get the number of tasks that panicked
+func (e *Executor) Panics() int64 {
+	return atomic.LoadInt64(&e.panics)
+}
+
+// Checks if a task is a Runnable or a Callable
+func IsTask(task interface{}) bool {
+	switch task.(type) {
+	case Runnable, Callable:
+		return true
+	}
+	return false
+}
+
 // Shuts down the executor
 func (e *Executor) Shutdown() {
 	e.wg.Wait()
-	close(e.shutdown)
+	e.stop()
+}
+
+// Signals the workers to return. Holding the pool lock keeps a resize from
+// starting workers after the shutdown.
+func (e *Executor) stop() {
+	e.stopOnce.Do(func() {
+		e.poolLock.Lock()
+		close(e.shutdown)
+		e.poolLock.Unlock()
+	})
+}
+
+// Creates and starts the workers of the executor, and its context watcher if
+// it is bound to a context
+func (e *Executor) start(capacity int, run func(w *Worker)) {
+	if e.newDeque == nil {
+		e.newDeque = func() DEQueue { return NewUnBoundedDEQueue() }
+	}
+	e.run = run
+	e.terminated = make(chan interface{})
+	e.wake = make(chan struct{}, capacity)
+	e.poolLock.Lock()
+	for i := 0; i < capacity; i++ {
+		e.addWorker()
+	}
+	e.poolLock.Unlock()
+	go func() {
+		e.running.Wait()
+		close(e.terminated)
+	}()
+	if e.ctx != nil {
+		go e.watch()
+	}
 }
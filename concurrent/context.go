@@ -0,0 +1,145 @@
+//
+// context.go
+// Christian Jordan
+// Context-bound executors and immediate shutdown
+//
+
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithContext binds the executor to a context. Once the context is done the
+// executor shuts down immediately, and the Futures of the pending tasks
+// return the context's error.
+func WithContext(ctx context.Context) Option {
+	return func(e *Executor) { e.ctx = ctx }
+}
+
+// Shuts down the executor once its context is done
+func (e *Executor) watch() {
+	select {
+	case <-e.ctx.Done():
+		e.shutdownNow(e.ctx.Err())
+	case <-e.terminated:
+	}
+}
+
+// Futures submitted with the same cancellable context, cancelled together
+// once the context is done
+type ctxWatch struct {
+	ctx     context.Context
+	futures []*TaskFuture
+}
+
+// Watched contexts by their done channel
+type ctxWatches struct {
+	sync.Mutex
+	byDone map[<-chan struct{}]*ctxWatch
+}
+
+// Registers a future to be cancelled as soon as its context is done. Each
+// context is watched by one goroutine, which returns once the context is done
+// or the executor has terminated.
+func (e *Executor) watchFuture(f *TaskFuture) {
+	done := f.ctx.Done()
+	e.watches.Lock()
+	defer e.watches.Unlock()
+	w, ok := e.watches.byDone[done]
+	if !ok {
+		if e.watches.byDone == nil {
+			e.watches.byDone = make(map[<-chan struct{}]*ctxWatch)
+		}
+		w = &ctxWatch{ctx: f.ctx}
+		e.watches.byDone[done] = w
+		go e.watchContext(w)
+	}
+	// Completed futures are dropped before the list grows, so it stays about
+	// as long as the pending futures of the context
+	if len(w.futures) == cap(w.futures) {
+		w.futures = pendingFutures(w.futures)
+	}
+	w.futures = append(w.futures, f)
+}
+
+// Cancels the futures of a context once it is done
+func (e *Executor) watchContext(w *ctxWatch) {
+	select {
+	case <-w.ctx.Done():
+	case <-e.terminated:
+	}
+	e.watches.Lock()
+	delete(e.watches.byDone, w.ctx.Done())
+	futures := w.futures
+	e.watches.Unlock()
+	if err := w.ctx.Err(); err != nil {
+		for _, f := range futures {
+			f.cancel(err)
+		}
+	}
+}
+
+// Filters the futures that have not completed in place
+func pendingFutures(futures []*TaskFuture) []*TaskFuture {
+	pending := futures[:0]
+	for _, f := range futures {
+		if !f.IsDone() {
+			pending = append(pending, f)
+		}
+	}
+	for i := len(pending); i < len(futures); i++ {
+		futures[i] = nil
+	}
+	return pending
+}
+
+// Stops the executor without running its pending tasks, returning them
+func (e *Executor) ShutdownNow() []interface{} {
+	return e.shutdownNow(ErrCancelled)
+}
+
+// Stops the executor and drains its queues once the workers have returned,
+// cancelling the pending tasks with an error
+func (e *Executor) shutdownNow(err error) []interface{} {
+	// Stopping first wakes the producers blocked on a full queue
+	e.stop()
+	e.submitLock.Lock()
+	atomic.StoreInt32(&e.stopped, 1)
+	e.submitLock.Unlock()
+	e.running.Wait()
+
+	var pending []interface{}
+	queues := []DEQueue{e.globalQueue}
+	for _, worker := range e.allWorkers() {
+		queues = append(queues, worker.workerQueue)
+	}
+	for _, queue := range queues {
+		for !queue.IsEmpty() {
+			if task := queue.PopTop(); task != nil {
+				f := task.(*TaskFuture)
+				// Tasks cancelled by their own context are not pending
+				if f.cancel(err) {
+					pending = append(pending, f.task)
+				}
+				e.wg.Done()
+			}
+		}
+	}
+	return pending
+}
+
+// Waits at most a given time for the workers to return after a shutdown
+func (e *Executor) AwaitTermination(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-e.terminated:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
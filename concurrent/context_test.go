@@ -0,0 +1,61 @@
+//
+// context_test.go
+// Christian Jordan
+// Cancellation of queued futures by their context
+//
+
+package concurrent
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Futures queued behind a blocked worker complete as soon as their context is
+// cancelled, with one watching goroutine for all of them, and their tasks
+// never run
+func TestSubmitCtxCancelsQueuedFutures(t *testing.T) {
+	executors := map[string]func() ExecutorService{
+		"ws": func() ExecutorService { return NewWorkStealingExecutor(1, 10) },
+		"wb": func() ExecutorService { return NewWorkBalancingExecutor(1, 10, 2) },
+	}
+	for name, newExecutor := range executors {
+		e := newExecutor()
+		started, block := make(chan struct{}), make(chan struct{})
+		e.Submit(RunnableFunc(func() {
+			close(started)
+			<-block
+		}))
+		<-started
+
+		goroutines := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		ran := make(chan struct{}, 1000)
+		var futures []Future
+		for i := 0; i < 1000; i++ {
+			futures = append(futures, e.SubmitCtx(ctx, RunnableFunc(func() {
+				ran <- struct{}{}
+			})))
+		}
+		if n := runtime.NumGoroutine() - goroutines; n > 1 {
+			t.Fatalf("%s: %d goroutines watch one context", name, n)
+		}
+
+		cancel()
+		for _, f := range futures {
+			if _, ok := f.GetWithTimeout(time.Second); !ok {
+				t.Fatalf("%s: cancelled future not done", name)
+			}
+			if f.Err() != context.Canceled {
+				t.Fatalf("%s: future error %v", name, f.Err())
+			}
+		}
+		close(block)
+		e.Shutdown()
+		if len(ran) != 0 {
+			t.Fatalf("%s: %d cancelled tasks ran", name, len(ran))
+		}
+	}
+}
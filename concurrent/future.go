@@ -0,0 +1,125 @@
+//
+// future.go
+// Christian Jordan
+// Future of a submitted Runnable or Callable task
+//
+
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// PanicError is the error of a task that panicked
+type PanicError struct {
+	Value interface{} // Value passed to panic
+	Stack []byte      // Stack of the worker at the time of the panic
+}
+
+// Error describes the panic
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// ErrCancelled is the error of a task that was dropped before it ran
+var ErrCancelled = errors.New("task cancelled")
+
+// TaskFuture implements the Future interface. It is pushed into the executor
+// queues in place of its task, and completed by the worker that runs it or by
+// whoever cancels it first.
+type TaskFuture struct {
+	task    interface{}     // Runnable or Callable task
+	result  interface{}     // Value returned by a Callable, nil for a Runnable
+	err     error           // Error of a task that panicked or was cancelled
+	ctx     context.Context // Context dropping the task once done
+	done    chan struct{}   // Closed once the task has completed
+	claimed int32           // Set by the first to run or cancel the task
+}
+
+// NewTaskFuture creates a new TaskFuture of a Runnable or Callable task
+func NewTaskFuture(task interface{}) *TaskFuture {
+	return &TaskFuture{
+		task: task,
+		done: make(chan struct{}),
+	}
+}
+
+// Get waits for the task to complete and returns its result, or its error if
+// the task panicked or was cancelled
+func (f *TaskFuture) Get() interface{} {
+	<-f.done
+	return f.result
+}
+
+// GetWithTimeout waits at most a given time for the task to complete. Returns
+// the result and true, or nil and false if the task did not complete in time.
+func (f *TaskFuture) GetWithTimeout(timeout time.Duration) (interface{}, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-f.done:
+		return f.result, true
+	case <-timer.C:
+		return nil, false
+	}
+}
+
+// IsDone checks if the task has completed
+func (f *TaskFuture) IsDone() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Err waits for the task to complete and returns its error, nil if the task
+// completed normally
+func (f *TaskFuture) Err() error {
+	<-f.done
+	return f.err
+}
+
+// Claims the future for completion, false if it was already run or cancelled
+func (f *TaskFuture) claim() bool {
+	return atomic.CompareAndSwapInt32(&f.claimed, 0, 1)
+}
+
+// Runs the task, calling a Callable in preference to running it, and
+// completes the future. The caller has claimed the future. A panic of the
+// task is recovered and returned.
+func (f *TaskFuture) run() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.err = &PanicError{Value: r, Stack: debug.Stack()}
+			f.result = f.err
+		}
+		close(f.done)
+		err = f.err
+	}()
+	switch task := f.task.(type) {
+	case Callable:
+		f.result = task.Call()
+	case Runnable:
+		task.Run()
+	}
+	return nil
+}
+
+// Completes the future with an error without running the task, false if it
+// was already run or cancelled
+func (f *TaskFuture) cancel(err error) bool {
+	if !f.claim() {
+		return false
+	}
+	f.err = err
+	f.result = err
+	close(f.done)
+	return true
+}
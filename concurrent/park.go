@@ -0,0 +1,96 @@
+//
+// park.go
+// Christian Jordan
+// Parking of idle workers with exponential backoff
+//
+
+package concurrent
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	spinLimit = 64                    // Idle iterations spent spinning before parking
+	minPark   = 50 * time.Microsecond // First park duration of an idle streak
+	maxPark   = 10 * time.Millisecond // Longest park duration
+)
+
+// IdleStats is the time the workers of an executor spent without work
+type IdleStats struct {
+	Spin  time.Duration // Time spent spinning, looking for work
+	Park  time.Duration // Time spent parked
+	Parks int64         // Number of times a worker parked
+}
+
+// idler is the idle streak of a worker
+type idler struct {
+	spins int           // Idle iterations since the worker last ran a task
+	park  time.Duration // Duration of the next park
+	since time.Time     // Start of the current spin
+}
+
+// Called when a worker found no task to run. The worker spins for a while,
+// then parks until work is signalled, the executor shuts down, or a backoff
+// doubling with every park expires.
+func (e *Executor) idle(w *Worker, s *idler) {
+	if s.spins == 0 {
+		s.since = time.Now()
+	}
+	s.spins++
+	if s.spins <= spinLimit {
+		runtime.Gosched()
+		return
+	}
+
+	now := time.Now()
+	atomic.AddInt64(&w.spinTime, int64(now.Sub(s.since)))
+	if s.park == 0 {
+		s.park = minPark
+	} else if s.park < maxPark {
+		s.park *= 2
+	}
+
+	timer := time.NewTimer(s.park)
+	select {
+	case <-e.wake:
+		s.park = 0
+	case <-e.shutdown:
+	case <-w.retire:
+	case <-timer.C:
+	}
+	timer.Stop()
+	atomic.AddInt64(&w.parkTime, int64(time.Since(now)))
+	atomic.AddInt64(&w.parks, 1)
+	s.spins = 0
+}
+
+// Called when a worker ran a task, ending its idle streak
+func (e *Executor) busy(w *Worker, s *idler) {
+	if s.spins > 0 {
+		atomic.AddInt64(&w.spinTime, int64(time.Since(s.since)))
+	}
+	s.spins = 0
+	s.park = 0
+}
+
+// Wakes a parked worker, if any, after work was pushed to a queue
+func (e *Executor) signal() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Get the time the workers spent spinning and parked
+func (e *Executor) IdleStats() IdleStats {
+	var stats IdleStats
+	for _, w := range e.allWorkers() {
+		stats.Spin += time.Duration(atomic.LoadInt64(&w.spinTime))
+		stats.Park += time.Duration(atomic.LoadInt64(&w.parkTime))
+		stats.Parks += atomic.LoadInt64(&w.parks)
+	}
+	return stats
+}
@@ -0,0 +1,56 @@
+//
+// park_test.go
+// Christian Jordan
+// Idle streaks of workers that find work again
+//
+
+package concurrent
+
+import (
+	"context"
+	"testing"
+)
+
+// A worker that grabs from the global queue after a long idle period runs the
+// grabbed tasks without parking again
+func TestGrabEndsIdleStreak(t *testing.T) {
+	e := &Executor{
+		globalQueue: NewUnBoundedDEQueue(),
+		shutdown:    make(chan struct{}),
+		wake:        make(chan struct{}, 1),
+	}
+	w := &Worker{workerQueue: NewUnBoundedDEQueue(), retire: make(chan struct{})}
+	e.workers = []*Worker{w}
+
+	// Park until the backoff is long, then spin up to the next park
+	var idle idler
+	for w.parks < 8 {
+		e.idle(w, &idle)
+	}
+	for i := 0; i < spinLimit; i++ {
+		e.idle(w, &idle)
+	}
+
+	futures := make([]*TaskFuture, 100)
+	for i := range futures {
+		futures[i] = NewTaskFuture(RunnableFunc(func() {}))
+		futures[i].ctx = context.Background()
+		e.wg.Add(1)
+		e.globalQueue.PushBottom(futures[i])
+	}
+	for !e.globalQueue.IsEmpty() || !w.workerQueue.IsEmpty() {
+		if e.popCallTask(w) {
+			e.busy(w, &idle)
+		} else {
+			e.idle(w, &idle)
+		}
+	}
+	if w.parks != 8 {
+		t.Fatalf("worker parked %d times with grabbed work", w.parks-8)
+	}
+	for _, f := range futures {
+		if !f.IsDone() {
+			t.Fatal("grabbed task did not run")
+		}
+	}
+}
@@ -0,0 +1,114 @@
+//
+// resize.go
+// Christian Jordan
+// Growing and shrinking the pool of an executor at runtime
+//
+
+package concurrent
+
+import (
+	"errors"
+)
+
+// ErrPoolSize is returned when resizing a pool to less than one worker
+var ErrPoolSize = errors.New("pool needs at least one worker")
+
+// ErrShutdown is returned when resizing an executor that was shut down
+var ErrShutdown = errors.New("executor is shut down")
+
+// Resize grows or shrinks the pool to n workers. New workers start with an
+// empty deque, the last workers of the pool are retired first. Retired workers
+// finish their running task and push their queued tasks to the global queue
+// before Resize returns.
+func (e *Executor) Resize(n int) error {
+	if n < 1 {
+		return ErrPoolSize
+	}
+	e.poolLock.Lock()
+	if isClosed(e.shutdown) {
+		e.poolLock.Unlock()
+		return ErrShutdown
+	}
+	var retiring []*Worker
+	if n < len(e.workers) {
+		// The pool is copied, victims picked from the old pool stay valid
+		retiring = e.workers[n:]
+		e.workers = append([]*Worker(nil), e.workers[:n]...)
+		e.retired = append(e.retired, retiring...)
+		for _, w := range retiring {
+			close(w.retire)
+		}
+	}
+	for len(e.workers) < n {
+		e.addWorker()
+	}
+	e.poolLock.Unlock()
+
+	for _, w := range retiring {
+		<-w.done
+	}
+	return nil
+}
+
+// Creates a worker at the end of the pool and starts it, the caller holds the
+// pool lock
+func (e *Executor) addWorker() {
+	w := &Worker{
+		id:          len(e.workers),
+		workerQueue: e.newDeque(),
+		retire:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	e.workers = append(e.workers, w)
+	e.running.Add(1)
+	go func() {
+		defer e.running.Done()
+		defer close(w.done)
+		e.run(w)
+		if isClosed(w.retire) {
+			e.drain(w)
+		}
+	}()
+}
+
+// Pushes the queued tasks of a retired worker to the global queue. Tasks that
+// a shutdown keeps out of a full bounded queue are cancelled.
+func (e *Executor) drain(w *Worker) {
+	for !w.workerQueue.IsEmpty() {
+		task := w.workerQueue.PopTop()
+		if task == nil {
+			continue
+		}
+		if e.bounded == nil {
+			e.globalQueue.PushBottom(task)
+		} else if !e.bounded.PushBottomWait(task, e.shutdown, nil) {
+			task.(*TaskFuture).cancel(ErrCancelled)
+			e.wg.Done()
+		}
+	}
+	e.signal()
+}
+
+// Get the workers in the pool
+func (e *Executor) pool() []*Worker {
+	e.poolLock.RLock()
+	defer e.poolLock.RUnlock()
+	return e.workers
+}
+
+// Get the workers in the pool followed by the retired workers
+func (e *Executor) allWorkers() []*Worker {
+	e.poolLock.RLock()
+	defer e.poolLock.RUnlock()
+	return append(append([]*Worker(nil), e.workers...), e.retired...)
+}
+
+// Checks if a channel is closed
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
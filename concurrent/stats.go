@@ -0,0 +1,147 @@
+//
+// stats.go
+// Christian Jordan
+// Executor statistics and per-worker telemetry
+//
+
+package concurrent
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// Number of buckets of a queue depth histogram. Bucket 0 counts empty queues
+// and bucket i counts depths in [2^(i-1), 2^i), the last bucket counts the
+// rest.
+const DepthBuckets = 16
+
+// WorkerStats is a snapshot of the telemetry of one worker
+type WorkerStats struct {
+	Executed      int64               // Tasks run
+	Grabbed       int64               // Tasks grabbed from the global queue
+	StealAttempts int64               // Attempts to steal from a victim
+	Steals        int64               // Attempts that stole at least one task
+	Stolen        int64               // Tasks stolen
+	Balances      int64               // Balance operations with a victim
+	Spin          time.Duration       // Time spent spinning without work
+	Park          time.Duration       // Time spent parked
+	Parks         int64               // Number of times the worker parked
+	Depth         [DepthBuckets]int64 // Local queue depth when a task is popped or grabbed
+	Retired       bool                // Whether a resize retired the worker
+}
+
+// Stats is a snapshot of the telemetry of an executor
+type Stats struct {
+	Workers []WorkerStats // Telemetry of every worker, retired workers last
+	Panics  int64         // Number of tasks that panicked
+}
+
+// workerCounters are the telemetry counters of a worker, updated atomically
+type workerCounters struct {
+	grabbed       int64
+	stealAttempts int64
+	steals        int64
+	stolen        int64
+	balances      int64
+	depth         [DepthBuckets]int64
+}
+
+// Record the depth of a worker's local queue
+func (c *workerCounters) sampleDepth(depth int32) {
+	bucket := bits.Len32(uint32(depth))
+	if bucket >= DepthBuckets {
+		bucket = DepthBuckets - 1
+	}
+	atomic.AddInt64(&c.depth[bucket], 1)
+}
+
+// Stats returns a snapshot of the telemetry of the executor
+func (e *Executor) Stats() Stats {
+	stats := Stats{Panics: e.Panics()}
+	for _, w := range e.allWorkers() {
+		ws := WorkerStats{
+			Executed:      atomic.LoadInt64(&w.tst),
+			Grabbed:       atomic.LoadInt64(&w.grabbed),
+			StealAttempts: atomic.LoadInt64(&w.stealAttempts),
+			Steals:        atomic.LoadInt64(&w.steals),
+			Stolen:        atomic.LoadInt64(&w.stolen),
+			Balances:      atomic.LoadInt64(&w.balances),
+			Spin:          time.Duration(atomic.LoadInt64(&w.spinTime)),
+			Park:          time.Duration(atomic.LoadInt64(&w.parkTime)),
+			Parks:         atomic.LoadInt64(&w.parks),
+			Retired:       isClosed(w.retire),
+		}
+		for b := range ws.Depth {
+			ws.Depth[b] = atomic.LoadInt64(&w.depth[b])
+		}
+		stats.Workers = append(stats.Workers, ws)
+	}
+	return stats
+}
+
+// Total returns the sum of the telemetry of all workers
+func (s Stats) Total() WorkerStats {
+	var total WorkerStats
+	for _, w := range s.Workers {
+		total.Executed += w.Executed
+		total.Grabbed += w.Grabbed
+		total.StealAttempts += w.StealAttempts
+		total.Steals += w.Steals
+		total.Stolen += w.Stolen
+		total.Balances += w.Balances
+		total.Spin += w.Spin
+		total.Park += w.Park
+		total.Parks += w.Parks
+		for b := range w.Depth {
+			total.Depth[b] += w.Depth[b]
+		}
+	}
+	return total
+}
+
+// MeanDepth returns the mean sampled queue depth, taking the lower bound of
+// every histogram bucket
+func (w WorkerStats) MeanDepth() float64 {
+	var samples, sum int64
+	for b, n := range w.Depth {
+		low := int64(0)
+		if b > 0 {
+			low = 1 << (b - 1)
+		}
+		samples += n
+		sum += n * low
+	}
+	if samples == 0 {
+		return 0
+	}
+	return float64(sum) / float64(samples)
+}
+
+// WriteTable writes the telemetry as a table with one row per worker and a
+// total row
+func (s Stats) WriteTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "worker\texecuted\tgrabbed\tsteals\tstolen\tbalances\t"+
+		"spin\tpark\tparks\tdepth\t")
+	row := func(name string, ws WorkerStats) {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d/%d\t%d\t%d\t%v\t%v\t%d\t%.1f\t\n",
+			name, ws.Executed, ws.Grabbed, ws.Steals, ws.StealAttempts, ws.Stolen,
+			ws.Balances, ws.Spin.Round(time.Microsecond),
+			ws.Park.Round(time.Microsecond), ws.Parks, ws.MeanDepth())
+	}
+	for i, ws := range s.Workers {
+		if ws.Retired {
+			row(fmt.Sprintf("%d (retired)", i), ws)
+		} else {
+			row(fmt.Sprint(i), ws)
+		}
+	}
+	row("total", s.Total())
+	fmt.Fprintf(table, "panics\t%d\t\n", s.Panics)
+	return table.Flush()
+}
@@ -7,64 +7,95 @@
 package concurrent
 
 import (
-	"math/rand"
 	"sync"
+	"sync/atomic"
 )
 
 // NewWorkStealingExecutor returns an Executor that is implemented using the
 // work-stealing algorithm.
 // @param capacity - The number of goroutines in the pool
 // @param threshold - The number of items that a goroutine in the pool can
-// grab from the executor in one time period.
-func NewWorkStealingExecutor(capacity, threshold int) ExecutorService {
-	var workers []Worker
-	for i := 0; i < capacity; i++ {
-		workers = append(workers, Worker{workerQueue: NewUnBoundedDEQueue()})
-	}
+// grab from the executor in one time period. By default a goroutine steals
+// this many items at once, from victims holding more.
+// @param opts - Options of the executor, such as its steal options.
+func NewWorkStealingExecutor(capacity, threshold int,
+	opts ...Option,
+) ExecutorService {
 	executor := &Executor{
-		workers:        workers,
 		globalQueue:    NewUnBoundedDEQueue(),
-		shutdown:       make(chan interface{}),
+		shutdown:       make(chan struct{}),
 		thresholdQueue: threshold,
 		wg:             sync.WaitGroup{},
 	}
-	for worker := 0; worker < capacity; worker++ {
-		go executor.runWorkStealer(worker)
+	for _, opt := range opts {
+		opt(executor)
+	}
+	if executor.steal.Policy == nil {
+		executor.steal.Policy = FixedBatch{
+			Size: int32(threshold),
+			Min:  int32(threshold) + 1,
+		}
+	}
+	if executor.steal.Victims == nil {
+		executor.steal.Victims = NewRandomVictim
 	}
+	executor.start(capacity, executor.runWorkStealer)
 	return executor
 }
 
 // runWorkStealer is the main worker instructions for the worker stealing routine
-func (e *Executor) runWorkStealer(me int) {
+func (e *Executor) runWorkStealer(me *Worker) {
+	var idle idler
+	victims := e.steal.Victims()
 	for {
 		select {
 		case <-e.shutdown:
 			return
+		case <-me.retire:
+			return
 		default:
 			// Attempt to pop and call a task from local queue
 			successfulCall := e.popCallTask(me)
 
 			// If local queue is empty, steal from another worker
 			if !successfulCall {
-				var randSteal int
-				for {
-					randSteal = rand.Intn(len(e.workers))
-					if randSteal == me {
-						continue
-					}
-					break
-				}
-				// Steal from random worker, if they have greater than 500 tasks
-				steal := 1000
-				if e.workers[randSteal].workerQueue.Size() > int32(steal) {
-					for i := 0; i < steal; i++ {
-						task := e.workers[randSteal].workerQueue.PopTop()
-						if task != nil {
-							e.workers[me].workerQueue.PushBottom(task)
-						}
-					}
+				e.poolLock.RLock()
+				if victim := victims.Next(me.id, len(e.workers)); victim >= 0 {
+					stolen := e.stealFrom(e.workers[victim], me)
+					victims.Result(victim, stolen > 0)
+					successfulCall = stolen > 0
 				}
+				e.poolLock.RUnlock()
 			}
+
+			// Park after spinning for a while without work
+			if successfulCall {
+				e.busy(me, &idle)
+			} else {
+				e.idle(me, &idle)
+			}
+		}
+	}
+}
+
+// Steals tasks from a victim's queue into my queue as the steal policy
+// decides, returning the number of tasks stolen. The caller holds the pool
+// lock.
+func (e *Executor) stealFrom(victim, me *Worker) int64 {
+	atomic.AddInt64(&me.stealAttempts, 1)
+	amount := e.steal.Policy.Amount(victim.workerQueue.Size())
+	stolen := int64(0)
+	for i := int32(0); i < amount; i++ {
+		task := victim.workerQueue.PopTop()
+		if task == nil {
+			break
 		}
+		me.workerQueue.PushBottom(task)
+		stolen++
+	}
+	if stolen > 0 {
+		atomic.AddInt64(&me.steals, 1)
+		atomic.AddInt64(&me.stolen, stolen)
 	}
+	return stolen
 }
@@ -0,0 +1,168 @@
+//
+// stealpolicy.go
+// Christian Jordan
+// Steal policies and victim selection of the work stealing executor
+//
+
+package concurrent
+
+import (
+	"math/rand"
+)
+
+// StealPolicy decides how many tasks a thief takes from a victim
+type StealPolicy interface {
+	// Amount returns the number of tasks to steal from a victim holding size
+	// tasks, 0 to leave the victim alone
+	Amount(size int32) int32
+}
+
+// VictimSelector picks the victims of one worker. Every worker has its own
+// VictimSelector, so implementations need not be safe for concurrent use.
+type VictimSelector interface {
+	// Next returns the next victim of worker me among n workers, -1 if there
+	// is no other worker
+	Next(me, n int) int
+	// Result reports whether stealing from a victim returned any task
+	Result(victim int, stolen bool)
+}
+
+// StealOptions configures the work stealing executor
+type StealOptions struct {
+	Policy  StealPolicy           // Number of tasks to steal, a batch of the threshold by default
+	Victims func() VictimSelector // Creates the victim selector of a worker, random by default
+}
+
+// WithStealOptions sets the steal policy and victim selection of the work
+// stealing executor
+func WithStealOptions(opts StealOptions) Option {
+	return func(e *Executor) { e.steal = opts }
+}
+
+// StealOne steals a single task
+type StealOne struct{}
+
+// Amount returns 1 if the victim has a task
+func (StealOne) Amount(size int32) int32 {
+	if size > 0 {
+		return 1
+	}
+	return 0
+}
+
+// StealHalf steals half of the victim's tasks, rounded up
+type StealHalf struct{}
+
+// Amount returns half of the victim's tasks
+func (StealHalf) Amount(size int32) int32 {
+	return (size + 1) / 2
+}
+
+// FixedBatch steals Size tasks, or all of them if there are fewer, from
+// victims holding at least Min tasks
+type FixedBatch struct {
+	Size int32 // Number of tasks stolen at once
+	Min  int32 // Smallest victim worth stealing from
+}
+
+// Amount returns the batch size if the victim is large enough
+func (p FixedBatch) Amount(size int32) int32 {
+	if size < p.Min || size == 0 {
+		return 0
+	}
+	if size < p.Size {
+		return size
+	}
+	return p.Size
+}
+
+// AdaptiveSteal steals half of the tasks of victims holding at least Min
+// tasks, at most Max tasks at once
+type AdaptiveSteal struct {
+	Min int32 // Smallest victim worth stealing from
+	Max int32 // Largest number of tasks stolen at once, 0 for no limit
+}
+
+// Amount returns half of the victim's tasks within the bounds
+func (p AdaptiveSteal) Amount(size int32) int32 {
+	if size < p.Min || size == 0 {
+		return 0
+	}
+	amount := (size + 1) / 2
+	if p.Max > 0 && amount > p.Max {
+		amount = p.Max
+	}
+	return amount
+}
+
+// RandomVictim picks a uniformly random other worker
+type RandomVictim struct{}
+
+// NewRandomVictim creates a RandomVictim selector
+func NewRandomVictim() VictimSelector { return RandomVictim{} }
+
+// Next returns a random worker other than me
+func (RandomVictim) Next(me, n int) int {
+	if n < 2 {
+		return -1
+	}
+	victim := rand.Intn(n - 1)
+	if victim >= me {
+		victim++
+	}
+	return victim
+}
+
+// Result is ignored
+func (RandomVictim) Result(int, bool) {}
+
+// RoundRobinVictim visits the other workers in turn
+type RoundRobinVictim struct {
+	last int // Last victim
+}
+
+// NewRoundRobinVictim creates a RoundRobinVictim selector
+func NewRoundRobinVictim() VictimSelector { return &RoundRobinVictim{} }
+
+// Next returns the worker after the last victim, skipping me
+func (s *RoundRobinVictim) Next(me, n int) int {
+	if n < 2 {
+		return -1
+	}
+	s.last = (s.last + 1) % n
+	if s.last == me {
+		s.last = (s.last + 1) % n
+	}
+	return s.last
+}
+
+// Result is ignored
+func (s *RoundRobinVictim) Result(int, bool) {}
+
+// LastSuccessfulVictim returns to the last victim it stole from, and picks a
+// random victim after a failed steal
+type LastSuccessfulVictim struct {
+	last int // Last victim stolen from, -1 if the last steal failed
+}
+
+// NewLastSuccessfulVictim creates a LastSuccessfulVictim selector
+func NewLastSuccessfulVictim() VictimSelector {
+	return &LastSuccessfulVictim{last: -1}
+}
+
+// Next returns the last successful victim, or a random worker other than me
+func (s *LastSuccessfulVictim) Next(me, n int) int {
+	if s.last >= 0 && s.last < n && s.last != me {
+		return s.last
+	}
+	return RandomVictim{}.Next(me, n)
+}
+
+// Result remembers a successful victim
+func (s *LastSuccessfulVictim) Result(victim int, stolen bool) {
+	if stolen {
+		s.last = victim
+	} else {
+		s.last = -1
+	}
+}
@@ -9,12 +9,25 @@ package concurrent
 import (
 	"pp_project/config"
 	"pp_project/pathfind"
+	"sync/atomic"
 )
 
 // UpdateTask updates the config space with a new sample point. It implemnents
 // the Callable interface
 type UpdateTask struct {
-	ctx *config.ConfigSpace // Config space to update
+	ctx   *config.ConfigSpace // Config space to update
+	trace Recorder            // Optional recorder of the planner progress
+	stop  Stopper             // Optional stopping criteria of the run
+}
+
+// Recorder records the state of a config space after a number of samples
+type Recorder interface {
+	Record(iteration int, space *config.ConfigSpace)
+}
+
+// Stopper decides when a run on a config space should take no more samples
+type Stopper interface {
+	Done(space *config.ConfigSpace) bool
 }
 
 // NewUpdateTask creates a new UpdateTask
@@ -22,35 +35,57 @@ func NewUpdateTask(ctx *config.ConfigSpace) *UpdateTask {
 	return &UpdateTask{ctx: ctx}
 }
 
+// NewTracedUpdateTask creates a new UpdateTask that reports to a Recorder
+// once it has run
+func NewTracedUpdateTask(ctx *config.ConfigSpace, trace Recorder) *UpdateTask {
+	return &UpdateTask{ctx: ctx, trace: trace}
+}
+
+// NewAnytimeUpdateTask creates a new traced UpdateTask that takes no sample
+// once the Stopper is done
+func NewAnytimeUpdateTask(ctx *config.ConfigSpace,
+	trace Recorder,
+	stop Stopper,
+) *UpdateTask {
+	return &UpdateTask{ctx: ctx, trace: trace, stop: stop}
+}
+
+// Get the cost of the best reached goal
 func (t *UpdateTask) GetDistToGoal() float32 {
 	return t.ctx.Path.GetDistToGoal()
 }
 
+// Call runs the task and returns the distance to goal once it has run
+func (t *UpdateTask) Call() interface{} {
+	t.Run()
+	return t.GetDistToGoal()
+}
+
 // Run the task
 func (t *UpdateTask) Run() {
+	if t.stop != nil && t.stop.Done(t.ctx) {
+		return
+	}
+	// Obstacles may not change while the tree is being updated
+	t.ctx.Lock.RLock()
+	defer t.ctx.Lock.RUnlock()
+	iteration := atomic.AddInt64(&t.ctx.Samples, 1)
+
 	var sample *config.MileStone
 	point := pathfind.SamplePoint(t.ctx)
 	if t.ctx.Feasible(point) {
+		atomic.AddInt64(&t.ctx.Feasibles, 1)
 		// Create new MileStone
 		sample = config.NewMileStone(point)
 		// Run RRT* algorithm
 		pathfind.RRTstar(sample, t.ctx)
 	}
-}
-
-// TaskFuture implements the Future interface
-type TaskFuture struct {
-	task   *UpdateTask
-	result chan interface{}
-}
-
-func (f *TaskFuture) Get() interface{} {
-	return f.task.GetDistToGoal()
-}
-
-func NewTaskFuture(task interface{}) Future {
-	return &TaskFuture{
-		task:   task.(*UpdateTask),
-		result: make(chan interface{}, 1),
+	if t.ctx.PruneEvery > 0 && iteration%t.ctx.PruneEvery == 0 {
+		t.ctx.Path.TreeLock.Lock()
+		t.ctx.Path.Prune()
+		t.ctx.Path.TreeLock.Unlock()
+	}
+	if t.trace != nil {
+		t.trace.Record(int(iteration), t.ctx)
 	}
 }
@@ -0,0 +1,75 @@
+//
+// typed.go
+// Christian Jordan
+// Typed function tasks and futures
+//
+
+package concurrent
+
+import (
+	"time"
+)
+
+// RunnableFunc adapts a function to the Runnable interface
+type RunnableFunc func()
+
+// Run calls the function
+func (f RunnableFunc) Run() { f() }
+
+// CallableFunc adapts a function returning a value of type T to the Callable
+// interface
+type CallableFunc[T any] func() T
+
+// Call calls the function and returns its value
+func (f CallableFunc[T]) Call() interface{} { return f() }
+
+// TypedFuture is a Future whose result is of type T
+type TypedFuture[T any] struct {
+	future Future // Untyped future of the task
+}
+
+// SubmitFunc submits a function returning a value of type T for execution and
+// returns a TypedFuture of its value, or nil if the executor ignored the task
+func SubmitFunc[T any](e ExecutorService, f func() T) *TypedFuture[T] {
+	future := e.Submit(CallableFunc[T](f))
+	if future == nil {
+		return nil
+	}
+	return &TypedFuture[T]{future: future}
+}
+
+// Get waits for the function to return and returns its value, or the zero
+// value if it panicked or was cancelled
+func (f *TypedFuture[T]) Get() T {
+	if f.future.Err() != nil {
+		var zero T
+		return zero
+	}
+	return typed[T](f.future.Get())
+}
+
+// GetWithTimeout waits at most a given time for the function to return.
+// Returns its value and true, or the zero value and false on a timeout.
+func (f *TypedFuture[T]) GetWithTimeout(timeout time.Duration) (T, bool) {
+	if _, ok := f.future.GetWithTimeout(timeout); !ok {
+		var zero T
+		return zero, false
+	}
+	return f.Get(), true
+}
+
+// Err waits for the function to return and returns the error of a panic or
+// cancellation, nil if it returned normally
+func (f *TypedFuture[T]) Err() error { return f.future.Err() }
+
+// IsDone checks if the function has returned
+func (f *TypedFuture[T]) IsDone() bool { return f.future.IsDone() }
+
+// Converts a result to T, a nil result is the zero value
+func typed[T any](value interface{}) T {
+	if value == nil {
+		var zero T
+		return zero
+	}
+	return value.(T)
+}
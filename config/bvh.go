@@ -0,0 +1,202 @@
+// bvh.go
+// Christian Jordan
+// Bounding volume hierarchy over configuration space obstacles
+
+package config
+
+import (
+	"math"
+	"sort"
+)
+
+// Maximum number of obstacles stored in a BVH leaf
+const bvhLeafSize = 4
+
+// AABB is an axis aligned bounding box
+type AABB struct {
+	Min Point // Lower left corner
+	Max Point // Upper right corner
+}
+
+// BVH is an AABB tree used to speed up obstacle queries
+type BVH struct {
+	root *bvhNode // Root of the tree, nil if there are no obstacles
+}
+
+// bvhNode is a node of the AABB tree, leaves hold the obstacles
+type bvhNode struct {
+	box       AABB       // Bounds of all obstacles below the node
+	left      *bvhNode   // Left subtree
+	right     *bvhNode   // Right subtree
+	obstacles []Obstacle // Obstacles of a leaf node
+}
+
+// NewAABB creates a new AABB from its corners
+func NewAABB(minX, minY, maxX, maxY float32) AABB {
+	return AABB{Min: Point{minX, minY}, Max: Point{maxX, maxY}}
+}
+
+// Union returns the smallest AABB containing both boxes
+func (b AABB) Union(o AABB) AABB {
+	return NewAABB(min32(b.Min.X, o.Min.X), min32(b.Min.Y, o.Min.Y),
+		max32(b.Max.X, o.Max.X), max32(b.Max.Y, o.Max.Y))
+}
+
+// Contains checks if a point is inside the box
+func (b AABB) Contains(pt *Point) bool {
+	return pt.X >= b.Min.X && pt.X <= b.Max.X &&
+		pt.Y >= b.Min.Y && pt.Y <= b.Max.Y
+}
+
+// Distance returns the distance from a point to the box, zero inside
+func (b AABB) Distance(pt *Point) float32 {
+	dx := max32(max32(b.Min.X-pt.X, pt.X-b.Max.X), 0)
+	dy := max32(max32(b.Min.Y-pt.Y, pt.Y-b.Max.Y), 0)
+	return float32(math.Hypot(float64(dx), float64(dy)))
+}
+
+// IntersectsSegment checks if the segment from a to b crosses the box, using
+// the slab method
+func (b AABB) IntersectsSegment(a, c *Point) bool {
+	tMin, tMax := 0.0, 1.0
+	slab := func(start, end, lo, hi float32) bool {
+		d := float64(end - start)
+		if d == 0 {
+			return start >= lo && start <= hi
+		}
+		t1 := float64(lo-start) / d
+		t2 := float64(hi-start) / d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin = math.Max(tMin, t1)
+		tMax = math.Min(tMax, t2)
+		return tMin <= tMax
+	}
+	return slab(a.X, c.X, b.Min.X, b.Max.X) && slab(a.Y, c.Y, b.Min.Y, b.Max.Y)
+}
+
+// NewBVH builds an AABB tree over a set of obstacles
+func NewBVH(obstacles []Obstacle) *BVH {
+	if len(obstacles) == 0 {
+		return &BVH{}
+	}
+	// Copy so that sorting does not reorder the caller's obstacles
+	items := make([]Obstacle, len(obstacles))
+	copy(items, obstacles)
+	return &BVH{root: buildBVH(items)}
+}
+
+// Recursively splits obstacles at the median of the longest box axis
+func buildBVH(obstacles []Obstacle) *bvhNode {
+	box := obstacles[0].Bounds()
+	for _, o := range obstacles[1:] {
+		box = box.Union(o.Bounds())
+	}
+	node := &bvhNode{box: box}
+	if len(obstacles) <= bvhLeafSize {
+		node.obstacles = obstacles
+		return node
+	}
+
+	// Sort obstacle centers along the longest axis
+	splitX := box.Max.X-box.Min.X >= box.Max.Y-box.Min.Y
+	sort.Slice(obstacles, func(i, j int) bool {
+		bi, bj := obstacles[i].Bounds(), obstacles[j].Bounds()
+		if splitX {
+			return bi.Min.X+bi.Max.X < bj.Min.X+bj.Max.X
+		}
+		return bi.Min.Y+bi.Max.Y < bj.Min.Y+bj.Max.Y
+	})
+	mid := len(obstacles) / 2
+	node.left = buildBVH(obstacles[:mid])
+	node.right = buildBVH(obstacles[mid:])
+	return node
+}
+
+// Collision checks if a point collides with any obstacle in the tree
+func (t *BVH) Collision(pt *Point) bool {
+	return t.root != nil && t.root.collision(pt)
+}
+
+func (n *bvhNode) collision(pt *Point) bool {
+	if !n.box.Contains(pt) {
+		return false
+	}
+	if n.left == nil {
+		for _, o := range n.obstacles {
+			if o.Collision(pt) {
+				return true
+			}
+		}
+		return false
+	}
+	return n.left.collision(pt) || n.right.collision(pt)
+}
+
+// SegmentCollision checks if the segment from a to b crosses any obstacle
+func (t *BVH) SegmentCollision(a, b *Point) bool {
+	return t.root != nil && t.root.segmentCollision(a, b)
+}
+
+func (n *bvhNode) segmentCollision(a, b *Point) bool {
+	if !n.box.IntersectsSegment(a, b) {
+		return false
+	}
+	if n.left == nil {
+		for _, o := range n.obstacles {
+			if o.SegmentCollision(a, b) {
+				return true
+			}
+		}
+		return false
+	}
+	return n.left.segmentCollision(a, b) || n.right.segmentCollision(a, b)
+}
+
+// Distance returns the signed distance from a point to the closest obstacle
+func (t *BVH) Distance(pt *Point) float32 {
+	dist := float32(math.MaxFloat32)
+	if t.root != nil {
+		t.root.distance(pt, &dist)
+	}
+	return dist
+}
+
+// Branch and bound search that skips boxes farther than the best distance.
+// Boxes containing the point are always visited, since overlapping obstacles
+// may have a smaller (more negative) signed distance.
+func (n *bvhNode) distance(pt *Point, best *float32) {
+	if d := n.box.Distance(pt); d > 0 && d >= *best {
+		return
+	}
+	if n.left == nil {
+		for _, o := range n.obstacles {
+			if d := o.Distance(pt); d < *best {
+				*best = d
+			}
+		}
+		return
+	}
+	// Visit the closer child first to tighten the bound early
+	first, second := n.left, n.right
+	if second.box.Distance(pt) < first.box.Distance(pt) {
+		first, second = second, first
+	}
+	first.distance(pt, best)
+	second.distance(pt, best)
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,156 @@
+// bvh_test.go
+// Christian Jordan
+// Benchmarks of the BVH against a linear scan on large random scenes
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Queries answered by both the BVH and the linear scan
+type obstacleQueries interface {
+	Collision(*Point) bool
+	SegmentCollision(*Point, *Point) bool
+}
+
+// linearScan answers the queries of a BVH by checking every obstacle, the
+// reference the tree is benchmarked against
+type linearScan []Obstacle
+
+// Collision checks if a point collides with any obstacle
+func (l linearScan) Collision(pt *Point) bool {
+	for _, o := range l {
+		if o.Collision(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// SegmentCollision checks if the segment from a to b crosses any obstacle
+func (l linearScan) SegmentCollision(a, b *Point) bool {
+	for _, o := range l {
+		if o.SegmentCollision(a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// Distance returns the signed distance from a point to the closest obstacle
+func (l linearScan) Distance(pt *Point) float32 {
+	dist := float32(math.MaxFloat32)
+	for _, o := range l {
+		if d := o.Distance(pt); d < dist {
+			dist = d
+		}
+	}
+	return dist
+}
+
+// Scene sizes of the benchmarks
+var benchmarkScenes = []int{1000, 5000}
+
+// Writes the config lines of a scene of the size of sample_input.txt with
+// random circles and rectangles, kept away from the start and goal, as
+// benchmark/random_scene.py does
+func randomScene(obstacles int, seed int64) []string {
+	r := rand.New(rand.NewSource(seed))
+	lines := []string{
+		"window,4000,8000",
+		"radius,15",
+		"delta,10",
+		"start,100,100",
+		"goal,7900,3900",
+	}
+	clear := func(x, y, size float64) bool {
+		for _, p := range [][2]float64{{100, 100}, {7900, 3900}} {
+			if math.Abs(x-p[0]) <= size+50 && math.Abs(y-p[1]) <= size+50 {
+				return false
+			}
+		}
+		return true
+	}
+	for len(lines) < 5+obstacles {
+		size := 5 + r.Float64()*55
+		x, y := r.Float64()*8000, r.Float64()*4000
+		if !clear(x, y, size) {
+			continue
+		}
+		if r.Intn(2) == 0 {
+			lines = append(lines, fmt.Sprintf("circle,%.1f,%.1f,%.1f", x, y, size))
+		} else {
+			lines = append(lines, fmt.Sprintf("rectangle,%.1f,%.1f,%.1f,%.1f",
+				x, y, size, 5+r.Float64()*55))
+		}
+	}
+	return lines
+}
+
+// Loads a random scene and creates query points with a segment of the
+// milestone step size from each
+func loadScene(b *testing.B, obstacles int) (*ConfigSpace, []*Point, []*Point) {
+	b.Helper()
+	space := newTestSpace(b, randomScene(obstacles, 0))
+	r := rand.New(rand.NewSource(1))
+	from := make([]*Point, 4096)
+	to := make([]*Point, len(from))
+	for i := range from {
+		from[i] = NewPoint(r.Float32()*space.WinWidth, r.Float32()*space.WinHeight)
+		to[i] = NewPoint(from[i].X+space.Path.DeltaDist, from[i].Y)
+	}
+	return space, from, to
+}
+
+// Runs the point and segment query of a sample against every scene size
+func benchmarkFeasible(b *testing.B, queries func(*ConfigSpace) obstacleQueries) {
+	for _, n := range benchmarkScenes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			space, from, to := loadScene(b, n)
+			q := queries(space)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				j := i % len(from)
+				if !q.Collision(from[j]) {
+					q.SegmentCollision(from[j], to[j])
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFeasibleLinear(b *testing.B) {
+	benchmarkFeasible(b, func(c *ConfigSpace) obstacleQueries {
+		return linearScan(c.Obstacles)
+	})
+}
+
+func BenchmarkFeasibleBVH(b *testing.B) {
+	benchmarkFeasible(b, func(c *ConfigSpace) obstacleQueries {
+		return c.Tree
+	})
+}
+
+// Checks that the BVH agrees with the linear scan it is measured against
+func TestBVHMatchesLinearScan(t *testing.T) {
+	space := newTestSpace(t, randomScene(1000, 0))
+	linear := linearScan(space.Obstacles)
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 10000; i++ {
+		a := NewPoint(r.Float32()*space.WinWidth, r.Float32()*space.WinHeight)
+		c := NewPoint(a.X+r.Float32()*100-50, a.Y+r.Float32()*100-50)
+		if space.Tree.Collision(a) != linear.Collision(a) {
+			t.Fatalf("point %v: collision differs", *a)
+		}
+		if space.Tree.SegmentCollision(a, c) != linear.SegmentCollision(a, c) {
+			t.Fatalf("segment %v %v: collision differs", *a, *c)
+		}
+		if space.Tree.Distance(a) != linear.Distance(a) {
+			t.Fatalf("point %v: distance differs", *a)
+		}
+	}
+}
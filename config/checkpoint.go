@@ -0,0 +1,209 @@
+// checkpoint.go
+// Christian Jordan
+// Binary checkpoints of the path plan tree and sampling progress
+
+package config
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"os"
+	"sync/atomic"
+)
+
+// Checkpoint file header, followed by the format version
+var checkpointMagic = [4]byte{'P', 'P', 'C', 'K'}
+
+const checkpointVersion uint32 = 1
+
+// Checkpoint is the sampling progress saved along with the tree
+type Checkpoint struct {
+	Samples   int64  // Number of samples taken
+	Feasibles int64  // Number of feasible samples
+	RandState uint64 // State of the sampling random number generator
+}
+
+// checkpointNode is the fixed size record of one milestone. Parent is the
+// index of the parent milestone, -1 for the root or an unreached goal.
+type checkpointNode struct {
+	Parent  int32
+	X, Y    float32
+	ParDist float32
+	Cost    float32
+}
+
+// Errors returned when reading a checkpoint
+var (
+	ErrCheckpointFormat   = errors.New("not a checkpoint file")
+	ErrCheckpointMismatch = errors.New("checkpoint does not match the configuration")
+)
+
+// WriteCheckpoint saves the tree, the applied obstacle events and the
+// sampling progress to a file. The tree and the events may not change while
+// they are written, so the caller holds the config space lock for reading and
+// the TreeLock, or the config space lock. The random state is taken by the
+// caller while other workers may still draw samples, so a resumed parallel
+// run is not reproducible sample for sample.
+func (c *ConfigSpace) WriteCheckpoint(filePath string, state uint64) error {
+	outFile, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	// Number the milestones, parents before children
+	var nodes []*MileStone
+	index := make(map[*MileStone]int32)
+	c.Path.walk(func(ms *MileStone) {
+		if !ms.goal {
+			index[ms] = int32(len(nodes))
+			nodes = append(nodes, ms)
+		}
+	})
+
+	writer := bufio.NewWriter(outFile)
+	header := []interface{}{
+		checkpointMagic,
+		checkpointVersion,
+		Checkpoint{
+			Samples:   atomic.LoadInt64(&c.Samples),
+			Feasibles: atomic.LoadInt64(&c.Feasibles),
+			RandState: state,
+		},
+		int32(c.nextEvent),
+		c.Path.Pruned(),
+		int32(len(c.Path.Goals)),
+		int32(len(nodes)),
+	}
+	for _, v := range header {
+		if err := binary.Write(writer, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	// Goals come first so that their count can be checked before the tree
+	goals := make([]*MileStone, len(c.Path.Goals))
+	for i, g := range c.Path.Goals {
+		goals[i] = g.Goal
+	}
+	for _, ms := range append(goals, nodes...) {
+		node := checkpointNode{-1, ms.point.X, ms.point.Y, ms.ParDist, ms.Cost}
+		if ms.parent != nil {
+			node.Parent = index[ms.parent]
+		}
+		if err := binary.Write(writer, binary.LittleEndian, node); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// ReadCheckpoint replaces the tree of a config space created from the same
+// configuration file with the tree of a checkpoint, and replays the obstacle
+// events applied before it was written. Returns the saved sampling progress.
+func (c *ConfigSpace) ReadCheckpoint(filePath string) (*Checkpoint, error) {
+	inFile, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer inFile.Close()
+	reader := bufio.NewReader(inFile)
+
+	var magic [4]byte
+	var version uint32
+	var progress Checkpoint
+	var nextEvent, goalCount, nodeCount int32
+	var pruned int64
+	header := []interface{}{
+		&magic, &version, &progress, &nextEvent, &pruned, &goalCount, &nodeCount,
+	}
+	for _, v := range header {
+		if err := binary.Read(reader, binary.LittleEndian, v); err != nil {
+			return nil, err
+		}
+	}
+	if magic != checkpointMagic || version != checkpointVersion {
+		return nil, ErrCheckpointFormat
+	}
+	if int(goalCount) != len(c.Path.Goals) || nodeCount < 1 ||
+		int(nextEvent) > len(c.Events) {
+		return nil, ErrCheckpointMismatch
+	}
+
+	records := make([]checkpointNode, goalCount+nodeCount)
+	if err := binary.Read(reader, binary.LittleEndian, records); err != nil {
+		return nil, err
+	}
+	goals, tree := records[:goalCount], records[goalCount:]
+	root := c.Path.pathHead.point
+	if tree[0].X != root.X || tree[0].Y != root.Y {
+		return nil, ErrCheckpointMismatch
+	}
+
+	// Rebuild the tree in new milestones, parents are always written before
+	// their children and only the root has no parent
+	nodes := make([]*MileStone, len(tree))
+	for i, node := range tree {
+		if node.Parent >= int32(i) || (node.Parent < 0) != (i == 0) {
+			return nil, ErrCheckpointFormat
+		}
+		nodes[i] = NewMileStone(NewPoint(node.X, node.Y))
+		if node.Parent >= 0 {
+			nodes[i].attach(nodes[node.Parent], node)
+		}
+	}
+	for _, node := range goals {
+		if node.Parent >= int32(len(nodes)) {
+			return nil, ErrCheckpointFormat
+		}
+	}
+
+	// The checkpoint is valid, the config space is only changed from here on
+	size := len(nodes)
+	for i, node := range goals {
+		goal := c.Path.Goals[i].Goal
+		goal.parent, goal.children = nil, NewChildrenList()
+		goal.Cost, goal.ParDist = 0, 0
+		if node.Parent >= 0 {
+			goal.point = NewPoint(node.X, node.Y)
+			goal.attach(nodes[node.Parent], node)
+			size++
+		}
+	}
+
+	// Obstacles changed by the applied events are restored without repair,
+	// the saved tree already accounts for them
+	for _, event := range c.Events[:nextEvent] {
+		if event.Add {
+			c.Obstacles = append(c.Obstacles, event.Obstacle)
+		} else {
+			for i, o := range c.Obstacles {
+				if o == event.Obstacle {
+					c.Obstacles = append(c.Obstacles[:i], c.Obstacles[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	if nextEvent > 0 {
+		c.rebuild()
+	}
+	c.nextEvent = int(nextEvent)
+
+	c.Path.pathHead = nodes[0]
+	atomic.StoreInt64(&c.Path.size, int64(size))
+	atomic.StoreInt64(&c.Path.pruned, pruned)
+	c.Path.resetGoals()
+	atomic.StoreInt64(&c.Samples, progress.Samples)
+	atomic.StoreInt64(&c.Feasibles, progress.Feasibles)
+	return &progress, nil
+}
+
+// Attach a milestone read from a checkpoint to its parent
+func (ms *MileStone) attach(parent *MileStone, node checkpointNode) {
+	ms.parent = parent
+	ms.ParDist = node.ParDist
+	ms.Cost = node.Cost
+	parent.SetChild(ms)
+}
@@ -0,0 +1,168 @@
+// checkpoint_test.go
+// Christian Jordan
+// Checkpoints taken while obstacle events fire, and rejected checkpoints
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Scene with obstacles appearing and vanishing over a comb shaped tree
+var eventScene = []string{
+	"window,1000,1000",
+	"radius,15",
+	"delta,10",
+	"start,100,100",
+	"goal,900,900",
+	"goal,900,100",
+	"dynamic,1,0,rectangle,140,140,20,20",
+	"dynamic,2,6,rectangle,200,95,10,10",
+	"dynamic,3,0,circle,250,150,12",
+	"dynamic,4,8,rectangle,300,160,30,30",
+	"dynamic,5,0,circle,160,190,8",
+}
+
+// Creates a config space from config lines
+func newTestSpace(t testing.TB, lines []string) *ConfigSpace {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.txt")
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	space, err := NewConfigSpace(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return space
+}
+
+// Grows a comb of milestones 10 apart from the path head: a row along x and
+// a column up from every milestone of the row. The second goal is reached
+// from the end of the row.
+func growComb(c *ConfigSpace) {
+	add := func(parent *MileStone, x, y float32) *MileStone {
+		ms := NewMileStone(NewPoint(x, y))
+		ms.SetParent(parent, 10)
+		ms.SetCost(parent.Cost + 10)
+		parent.SetChild(ms)
+		c.Path.AddedMileStone()
+		return ms
+	}
+	row := c.Path.pathHead
+	for i := 1; i <= 30; i++ {
+		row = add(row, row.point.X+10, 100)
+		column := row
+		for j := 1; j <= 10; j++ {
+			column = add(column, row.point.X, 100+float32(j)*10)
+		}
+	}
+	goal := c.Path.Goals[1]
+	c.Path.ConnectGoal(goal, row, CalcDistance(row.point, goal.Goal.point))
+}
+
+// Takes checkpoints the way a running task does while the events fire, each
+// checkpoint must restore the obstacles it was taken with
+func TestCheckpointWhileEventsFire(t *testing.T) {
+	space := newTestSpace(t, eventScene)
+	growComb(space)
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for sample := 0; space.PendingEvents(); sample++ {
+			space.ApplyEvents(sample)
+		}
+	}()
+
+	var obstacles [][]Obstacle
+	for i := 0; i < 50; i++ {
+		space.Lock.RLock()
+		space.Path.TreeLock.Lock()
+		obstacles = append(obstacles, append([]Obstacle(nil), space.Obstacles...))
+		err := space.WriteCheckpoint(filepath.Join(dir, fmt.Sprint(i)), 0)
+		space.Path.TreeLock.Unlock()
+		space.Lock.RUnlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	for i, want := range obstacles {
+		resumed := newTestSpace(t, eventScene)
+		if _, err := resumed.ReadCheckpoint(filepath.Join(dir, fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+		if len(resumed.Obstacles) != len(want) {
+			t.Fatalf("checkpoint %d: %d obstacles restored, %d applied",
+				i, len(resumed.Obstacles), len(want))
+		}
+		resumed.Path.walk(func(ms *MileStone) {
+			if !ms.goal && !resumed.Feasible(ms.point) {
+				t.Fatalf("checkpoint %d: milestone %v inside an obstacle", i, *ms.point)
+			}
+		})
+	}
+}
+
+// Corrupt or truncated checkpoints are rejected without changing the config
+// space
+func TestReadCheckpointRejectsCorruptFile(t *testing.T) {
+	space := newTestSpace(t, eventScene)
+	growComb(space)
+	space.ApplyEvents(3)
+	file := filepath.Join(t.TempDir(), "checkpoint")
+	if err := space.WriteCheckpoint(file, 0); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The header is 52 bytes, followed by 20 byte records of the goals
+	// first and then the tree
+	const header, record = 52, 20
+	badGoal := append([]byte(nil), data...)
+	badGoal[header+record] = 0xff
+	badGoal[header+record+1] = 0xff
+	badGoal[header+record+2] = 0xff
+	badGoal[header+record+3] = 0x7f
+	badNode := append([]byte(nil), data...)
+	badNode[header+3*record] = 0xff
+	badNode[header+3*record+1] = 0xff
+	badNode[header+3*record+2] = 0xff
+	badNode[header+3*record+3] = 0xff
+	corrupt := map[string][]byte{
+		"truncated header":  data[:header-4],
+		"truncated records": data[:len(data)-record/2],
+		"goal parent":       badGoal,
+		"orphan milestone":  badNode,
+	}
+
+	for name, bytes := range corrupt {
+		resumed := newTestSpace(t, eventScene)
+		growComb(resumed)
+		head, size := resumed.Path.pathHead, resumed.Path.Size()
+		reached := resumed.Path.Goals[1].Goal.parent
+		if err := os.WriteFile(file, bytes, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := resumed.ReadCheckpoint(file); err == nil {
+			t.Fatalf("%s: checkpoint accepted", name)
+		}
+		if resumed.Path.pathHead != head || resumed.Path.Size() != size ||
+			resumed.Path.Goals[1].Goal.parent != reached ||
+			resumed.nextEvent != 0 || len(resumed.Obstacles) != 0 {
+			t.Fatalf("%s: config space changed by a rejected checkpoint", name)
+		}
+	}
+}
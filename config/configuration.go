@@ -5,35 +5,62 @@
 package config
 
 import (
+	"fmt"
 	"math"
 	"pp_project/utils"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // ConfigSpace is a struct used for path planning
 type ConfigSpace struct {
-	Path       *PathPlan  // Root of the tree
-	Obstacles  []Obstacle // Obstacles in the configuration space
-	WinHeight  float32    // Window height
-	WinWidth   float32    // Window width
-	ConfigPath string     // Path to config file
+	Path       *PathPlan         // Root of the tree
+	Obstacles  []Obstacle        // Obstacles in the configuration space
+	Tree       *BVH              // Bounding volume hierarchy of the obstacles
+	Field      *DistanceField    // Signed distance field of the obstacles
+	Margin     float32           // Minimum clearance of a feasible point
+	Events     []ObstacleEvent   // Scheduled runtime obstacle changes
+	Moving     []*MovingObstacle // Obstacles following known trajectories
+	MaxSpeed   float32           // Robot speed used for arrival times
+	Robots     []Robot           // Robots planned together, empty for one robot
+	RobotSize  float32           // Radius of a robot
+	Samples    int64             // Number of samples taken, updated atomically
+	Feasibles  int64             // Number of feasible samples, updated atomically
+	PruneEvery int64             // Prune the tree every this many samples, 0 never
+	Lock       sync.RWMutex      // Held for writing while obstacles change
+	WinHeight  float32           // Window height
+	WinWidth   float32           // Window width
+	ConfigPath string            // Path to config file
+	nextEvent  int               // Index of the next scheduled event
 }
 
 // Obstacle is an interface for objects in the configuration space
 type Obstacle interface {
 	Draw()
 	Collision(*Point) bool
+	SegmentCollision(*Point, *Point) bool
+	Distance(*Point) float32 // Signed distance, negative inside the obstacle
+	Bounds() AABB
 }
 
-// Create a new configuration space from a config file
-func NewConfigSpace(configPath string) *ConfigSpace {
+// Create a new configuration space from a config file. Returns an error for
+// config lines that describe an impossible obstacle motion.
+func NewConfigSpace(configPath string) (*ConfigSpace, error) {
 	// Initialize variables
 	var winWidth, winHeight float64
 	var radius, delta float64
+	var margin, resolution float64
+	var speed, robotSize float64
 	var start *Point
 	var goal *Point
+	var goals []*Point
+	var regions []Region
 	var obstacles []Obstacle
+	var events []ObstacleEvent
+	var moving []*MovingObstacle
+	var robots []Robot
 
 	// Parse config file
 	config := utils.ReadFile(configPath)
@@ -47,58 +74,133 @@ func NewConfigSpace(configPath string) *ConfigSpace {
 			radius, _ = strconv.ParseFloat(line[1], 32)
 		} else if line[0] == "delta" {
 			delta, _ = strconv.ParseFloat(line[1], 32)
+		} else if line[0] == "margin" {
+			margin, _ = strconv.ParseFloat(line[1], 32)
+		} else if line[0] == "resolution" {
+			resolution, _ = strconv.ParseFloat(line[1], 32)
+		} else if line[0] == "speed" {
+			speed, _ = strconv.ParseFloat(line[1], 32)
+		} else if line[0] == "robotsize" {
+			robotSize, _ = strconv.ParseFloat(line[1], 32)
+		} else if line[0] == "robot" {
+			// robot,start x,start y,goal x,goal y
+			values := parseFloats(line[1:])
+			robots = append(robots, Robot{
+				Start: NewPoint(values[0], values[1]),
+				Goal:  NewPoint(values[2], values[3]),
+			})
 		} else if line[0] == "start" {
 			x, _ := strconv.ParseFloat(line[1], 32)
 			y, _ := strconv.ParseFloat(line[2], 32)
 			start = NewPoint(float32(x), float32(y))
 		} else if line[0] == "goal" {
+			// goal,x,y with an optional circle,r or rectangle,w,h region
 			x, _ := strconv.ParseFloat(line[1], 32)
 			y, _ := strconv.ParseFloat(line[2], 32)
-			goal = NewPoint(float32(x), float32(y))
-		} else if line[0] == "rectangle" {
-			x, _ := strconv.ParseFloat(line[1], 32)
-			y, _ := strconv.ParseFloat(line[2], 32)
-			w, _ := strconv.ParseFloat(line[3], 32)
-			h, _ := strconv.ParseFloat(line[4], 32)
-			obstacles = append(obstacles,
-				NewRectangle(float32(x), float32(y), float32(w), float32(h)))
-		} else if line[0] == "circle" {
-			x, _ := strconv.ParseFloat(line[1], 32)
-			y, _ := strconv.ParseFloat(line[2], 32)
-			r, _ := strconv.ParseFloat(line[3], 32)
-			obstacles = append(obstacles,
-				NewCircle(float32(x), float32(y), float32(r)))
+			goals = append(goals, NewPoint(float32(x), float32(y)))
+			regions = append(regions, parseRegion(float32(x), float32(y), line[3:]))
+		} else if line[0] == "rectangle" || line[0] == "circle" {
+			obstacles = append(obstacles, parseObstacle(line))
+		} else if line[0] == "moving" {
+			m, err := parseMovingObstacle(line[1:])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", strings.Join(line, ","), err)
+			}
+			moving = append(moving, m)
+		} else if line[0] == "dynamic" {
+			// dynamic,appear,vanish,shape... where vanish of 0 means never
+			appear, _ := strconv.Atoi(line[1])
+			vanish, _ := strconv.Atoi(line[2])
+			o := parseObstacle(line[3:])
+			events = append(events, ObstacleEvent{appear, o, true})
+			if vanish > 0 {
+				events = append(events, ObstacleEvent{vanish, o, false})
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Sample < events[j].Sample
+	})
+
+	// The first goal is the main goal, others are candidates
+	if len(goals) > 0 {
+		goal = goals[0]
+	}
+
+	// The start and goal pair is the first of several robots
+	if len(robots) > 0 {
+		robots = append([]Robot{{Start: start, Goal: goal}}, robots...)
+	}
+
+	// Moving obstacles need a robot speed to predict arrival times
+	if len(moving) > 0 && speed <= 0 {
+		speed = 1
+	}
+
+	// Robots are obstacles to each other, they need a size to collide
+	if len(robots) > 0 && robotSize <= 0 {
+		robotSize = delta
+	}
+
+	// Distance field resolution defaults to the milestone step size
+	var field *DistanceField
+	if resolution <= 0 {
+		resolution = delta
+	}
+	if resolution > 0 {
+		field = NewDistanceField(obstacles,
+			float32(winWidth),
+			float32(winHeight),
+			float32(resolution),
+		)
+	}
+
+	path := NewPathPlan(float32(delta), float32(radius), goal, start)
+	for i := range goals {
+		if i == 0 {
+			path.Goals[0].Region = regions[0]
+		} else {
+			path.AddGoal(goals[i], regions[i])
 		}
 	}
 
 	return &ConfigSpace{
-		Path: NewPathPlan(float32(delta),
-			float32(radius),
-			goal,
-			start,
-		),
+		Path:       path,
 		Obstacles:  obstacles,
+		Tree:       NewBVH(obstacles),
+		Field:      field,
+		Margin:     float32(margin),
+		Events:     events,
+		Moving:     moving,
+		MaxSpeed:   float32(speed),
+		Robots:     robots,
+		RobotSize:  float32(robotSize),
 		WinHeight:  float32(winHeight),
 		WinWidth:   float32(winWidth),
 		ConfigPath: "/data/",
-	}
-}
-
-// Add an obstacle to the configuration space
-func (c *ConfigSpace) AddObstacle(o Obstacle) {
-	c.Obstacles = append(c.Obstacles, o)
+	}, nil
 }
 
 // Check if a point is feasible in the configuration space
 func (c *ConfigSpace) Feasible(pt *Point) bool {
-	for _, o := range c.Obstacles {
-		if o.Collision(pt) {
-			return false
-		}
+	if c.Tree.Collision(pt) {
+		return false
+	}
+	// Reject points closer to an obstacle than the safety margin. The
+	// interpolated field overestimates clearance near convex obstacles, so the
+	// exact distance is used.
+	if c.Margin > 0 && c.Tree.Distance(pt) < c.Margin {
+		return false
 	}
 	return true
 }
 
+// Check if the straight segment between two points is feasible and keeps the
+// safety margin along its length
+func (c *ConfigSpace) FeasibleSegment(pt1 *Point, pt2 *Point) bool {
+	return !c.Tree.SegmentCollision(pt1, pt2) && c.SegmentKeepsMargin(pt1, pt2)
+}
+
 // Draw the configuration space
 func (c *ConfigSpace) Draw() {
 	c.Path.Draw()
@@ -107,6 +209,41 @@ func (c *ConfigSpace) Draw() {
 	}
 }
 
+// Parse a rectangle (x,y,w,h) or circle (x,y,r) obstacle config line
+func parseObstacle(line []string) Obstacle {
+	x, _ := strconv.ParseFloat(line[1], 32)
+	y, _ := strconv.ParseFloat(line[2], 32)
+	if line[0] == "rectangle" {
+		w, _ := strconv.ParseFloat(line[3], 32)
+		h, _ := strconv.ParseFloat(line[4], 32)
+		return NewRectangle(float32(x), float32(y), float32(w), float32(h))
+	}
+	r, _ := strconv.ParseFloat(line[3], 32)
+	return NewCircle(float32(x), float32(y), float32(r))
+}
+
+// Parse an optional goal region centered on a goal point
+func parseRegion(x, y float32, line []string) Region {
+	if len(line) == 0 {
+		return nil
+	}
+	values := parseFloats(line[1:])
+	if line[0] == "rectangle" {
+		return NewRectangle(x-values[0]/2, y-values[1]/2, values[0], values[1])
+	}
+	return NewCircle(x, y, values[0])
+}
+
+// Parse a list of config values as floats
+func parseFloats(line []string) []float32 {
+	values := make([]float32, len(line))
+	for i, v := range line {
+		f, _ := strconv.ParseFloat(v, 32)
+		values[i] = float32(f)
+	}
+	return values
+}
+
 // Calculate the distance between two points in the configuration space
 func CalcDistance(pt1 *Point, pt2 *Point) float32 {
 	return float32(math.Sqrt(math.Pow(float64(pt1.X-pt2.X), 2) +
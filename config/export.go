@@ -0,0 +1,162 @@
+// export.go
+// Christian Jordan
+// Export of the path plan tree to GraphML, DOT and JSON
+
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TreeNode is an exported milestone
+type TreeNode struct {
+	ID       int     `json:"id"`
+	X        float32 `json:"x"`
+	Y        float32 `json:"y"`
+	Cost     float32 `json:"cost"`
+	Goal     bool    `json:"goal"`
+	Solution bool    `json:"solution"` // Whether the milestone is on the solution path
+}
+
+// TreeEdge is an exported parent to child edge
+type TreeEdge struct {
+	Parent   int     `json:"parent"`
+	Child    int     `json:"child"`
+	ParDist  float32 `json:"par_dist"`
+	Solution bool    `json:"solution"` // Whether the edge is on the solution path
+}
+
+// TreeGraph is the milestone tree as lists of nodes and edges
+type TreeGraph struct {
+	Nodes []TreeNode `json:"nodes"`
+	Edges []TreeEdge `json:"edges"`
+}
+
+// Graph walks the tree from the path head and returns its nodes and edges,
+// with the path to the goal flagged as the solution
+func (path *PathPlan) Graph() *TreeGraph {
+	solution := make(map[*MileStone]bool)
+	if path.Goal.parent != nil {
+		for ms := path.Goal; ms != nil; ms = ms.parent {
+			solution[ms] = true
+		}
+	}
+
+	graph := &TreeGraph{}
+	ids := make(map[*MileStone]int)
+	path.walk(func(ms *MileStone) {
+		id := len(graph.Nodes)
+		ids[ms] = id
+		graph.Nodes = append(graph.Nodes, TreeNode{
+			ID:       id,
+			X:        ms.point.X,
+			Y:        ms.point.Y,
+			Cost:     ms.Cost,
+			Goal:     ms.goal,
+			Solution: solution[ms],
+		})
+		if ms.parent != nil {
+			graph.Edges = append(graph.Edges, TreeEdge{
+				Parent:   ids[ms.parent],
+				Child:    id,
+				ParDist:  ms.ParDist,
+				Solution: solution[ms],
+			})
+		}
+	})
+	return graph
+}
+
+// WriteJSON writes the graph as a JSON object of nodes and edges
+func (g *TreeGraph) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(g)
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Nodes are placed at
+// their milestone position and the solution path is drawn in red.
+func (g *TreeGraph) WriteDOT(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintln(writer, "digraph tree {")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(writer, "  %d [pos=\"%g,%g!\" cost=%g goal=%t solution=%t",
+			n.ID, n.X, n.Y, n.Cost, n.Goal, n.Solution)
+		if n.Solution {
+			fmt.Fprint(writer, " color=red")
+		}
+		fmt.Fprintln(writer, "];")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(writer, "  %d -> %d [par_dist=%g solution=%t",
+			e.Parent, e.Child, e.ParDist, e.Solution)
+		if e.Solution {
+			fmt.Fprint(writer, " color=red")
+		}
+		fmt.Fprintln(writer, "];")
+	}
+	fmt.Fprintln(writer, "}")
+	return writer.Flush()
+}
+
+// WriteGraphML writes the graph in GraphML
+func (g *TreeGraph) WriteGraphML(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintln(writer, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(writer, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	keys := [][3]string{
+		{"x", "node", "float"},
+		{"y", "node", "float"},
+		{"cost", "node", "float"},
+		{"goal", "node", "boolean"},
+		{"solution", "all", "boolean"},
+		{"par_dist", "edge", "float"},
+	}
+	for _, k := range keys {
+		fmt.Fprintf(writer, "  <key id=%q for=%q attr.name=%q attr.type=%q/>\n",
+			k[0], k[1], k[0], k[2])
+	}
+	fmt.Fprintln(writer, `  <graph id="tree" edgedefault="directed">`)
+	for _, n := range g.Nodes {
+		fmt.Fprintf(writer, "    <node id=\"n%d\">", n.ID)
+		fmt.Fprintf(writer, "<data key=\"x\">%g</data><data key=\"y\">%g</data>", n.X, n.Y)
+		fmt.Fprintf(writer, "<data key=\"cost\">%g</data>", n.Cost)
+		fmt.Fprintf(writer, "<data key=\"goal\">%t</data>", n.Goal)
+		fmt.Fprintf(writer, "<data key=\"solution\">%t</data></node>\n", n.Solution)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(writer, "    <edge source=\"n%d\" target=\"n%d\">", e.Parent, e.Child)
+		fmt.Fprintf(writer, "<data key=\"par_dist\">%g</data>", e.ParDist)
+		fmt.Fprintf(writer, "<data key=\"solution\">%t</data></edge>\n", e.Solution)
+	}
+	fmt.Fprintln(writer, "  </graph>")
+	fmt.Fprintln(writer, "</graphml>")
+	return writer.Flush()
+}
+
+// WriteFile writes the graph in the format given by the file extension,
+// (.graphml), (.dot) or (.json)
+func (g *TreeGraph) WriteFile(filePath string) error {
+	var write func(io.Writer) error
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".graphml":
+		write = g.WriteGraphML
+	case ".dot", ".gv":
+		write = g.WriteDOT
+	case ".json":
+		write = g.WriteJSON
+	default:
+		return fmt.Errorf("unknown tree export format %q", filepath.Ext(filePath))
+	}
+
+	outFile, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+	return write(outFile)
+}
@@ -0,0 +1,103 @@
+// goal.go
+// Christian Jordan
+// Goal regions and multi-goal queries
+
+package config
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Region is an area of the configuration space
+type Region interface {
+	Collision(*Point) bool // Checks if a point lies inside the region
+}
+
+// GoalRegion is a candidate goal of a path plan. A point goal (nil Region)
+// is reached by connecting a visible milestone to the goal point. A region
+// goal is reached at no extra cost by any milestone landing inside it.
+type GoalRegion struct {
+	Goal   *MileStone // Goal milestone, a leaf of the tree once reached
+	Region Region     // Tolerance region around the goal, nil for a point goal
+}
+
+// Add a candidate goal to the path plan
+func (path *PathPlan) AddGoal(pt *Point, region Region) *GoalRegion {
+	goal := NewMileStone(pt)
+	goal.goal = true
+	g := &GoalRegion{Goal: goal, Region: region}
+	path.Goals = append(path.Goals, g)
+	return g
+}
+
+// Checks if a milestone reaches a goal region
+func (g *GoalRegion) Contains(ms *MileStone) bool {
+	return g.Region != nil && g.Region.Collision(ms.point)
+}
+
+// Checks if the goal has been reached
+func (g *GoalRegion) Reached() bool {
+	return g.Goal.parent != nil
+}
+
+// ConnectGoal connects a goal as a child of a milestone at a given distance
+// if the goal is unreached or the milestone is a cheaper parent. A region goal
+// moves to the milestone it is reached from. Returns true if connected. The
+// caller holds the write side of the TreeLock.
+func (path *PathPlan) ConnectGoal(g *GoalRegion, ms *MileStone, dist float32) bool {
+	if g.Reached() && ms.Cost+dist >= g.Goal.Cost {
+		return false
+	}
+	if g.Region != nil {
+		g.Goal.point = NewPoint(ms.point.X, ms.point.Y)
+	}
+	if !g.Reached() {
+		path.AddedMileStone()
+	}
+	g.Goal.SetParent(ms, dist)
+	ms.SetChild(g.Goal)
+	g.Goal.SetCost(ms.Cost + dist)
+	path.MarkSolved()
+	path.SelectGoal()
+	return true
+}
+
+// SelectGoal makes the cheapest reached goal the goal of the path plan, or
+// the first goal if none is reached, and records its cost as the distance to
+// goal
+func (path *PathPlan) SelectGoal() {
+	best := path.Goals[0]
+	for _, g := range path.Goals {
+		if g.Reached() && (!best.Reached() || g.Goal.Cost < best.Goal.Cost) {
+			best = g
+		}
+	}
+	path.Goal = best.Goal
+
+	var dist float32
+	if best.Reached() {
+		dist = best.Goal.Cost
+	}
+	atomic.StoreUint32(&path.distToGoal, math.Float32bits(dist))
+}
+
+// CostToGo is a lower bound of the cost from a point to the nearest goal
+func (path *PathPlan) CostToGo(pt *Point) float32 {
+	best := float32(math.Inf(1))
+	for _, g := range path.Goals {
+		var dist float32
+		if region, ok := g.Region.(interface{ Distance(*Point) float32 }); ok {
+			dist = float32(math.Max(0, float64(region.Distance(pt))))
+		} else {
+			dist = CalcDistance(pt, g.Goal.point)
+		}
+		if dist < best {
+			best = dist
+		}
+	}
+	return best
+}
+
+// Checks if a milestone is one of the goals
+func (ms *MileStone) IsGoal() bool { return ms.goal }
@@ -17,6 +17,8 @@ type MileStone struct {
 	Lock     sync.Mutex         // Lock for milestone
 	Cost     float32            // Cost of the milestone
 	ParDist  float32            // Distance from parent
+	goal     bool               // Whether the milestone is a goal
+	pruned   bool               // Whether the milestone was pruned from the tree
 }
 
 // Create a new MileStone, assume point is feasible
@@ -32,6 +34,9 @@ func NewMileStone(pt *Point) *MileStone {
 // Set the point of a milestone
 func (ms *MileStone) GetPoint() *Point { return ms.point }
 
+// Get the parent of a milestone
+func (ms *MileStone) GetParent() *MileStone { return ms.parent }
+
 // Add a child to a milestone
 func (ms *MileStone) SetChild(c *MileStone) {
 	ms.children.Add(c)
@@ -45,12 +50,16 @@ func (ms *MileStone) RemoveChild(c *MileStone) {
 // Set the parent of a milestone
 func (ms *MileStone) SetParent(p *MileStone, dist float32) {
 	ms.Lock.Lock()
-	defer ms.Lock.Unlock()
-	if ms.parent != nil {
-		ms.parent.RemoveChild(ms)
-	}
+	old := ms.parent
 	ms.ParDist = dist
 	ms.parent = p
+	ms.Lock.Unlock()
+
+	// The old parent's children may be flagged for a branch update that
+	// needs this milestone's lock, so it is released first
+	if old != nil {
+		old.RemoveChild(ms)
+	}
 }
 
 func (ms *MileStone) SetCost(cost float32) {
@@ -64,6 +73,7 @@ func (ms *MileStone) UpdateCost(diff float32) {
 	costUpdate := func(m *MileStone) {
 		m.SetCost(m.Cost + diff)
 	}
+	costUpdate(ms)
 	BranchApply(ms.children, costUpdate)
 }
 
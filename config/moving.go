@@ -0,0 +1,205 @@
+// moving.go
+// Christian Jordan
+// Time-parameterised moving obstacles and space-time feasibility checks
+
+package config
+
+import (
+	"errors"
+	"math"
+)
+
+// Motion describes the offset of a moving obstacle over time
+type Motion interface {
+	Offset(t float32) *Point
+}
+
+// LinearMotion moves an obstacle from a start offset at a constant velocity
+type LinearMotion struct {
+	Start    Point // Offset at time zero
+	Velocity Point // Offset change per unit of time
+}
+
+// WaypointMotion moves an obstacle linearly between timed waypoints. The
+// obstacle rests at the first and last waypoints outside of their times.
+type WaypointMotion struct {
+	Times  []float32 // Increasing waypoint times
+	Points []Point   // Waypoint offsets
+}
+
+// MovingObstacle is a shape whose position follows a known motion
+type MovingObstacle struct {
+	shape  Obstacle // Shape placed relative to the motion offset
+	motion Motion   // Motion of the shape over time
+}
+
+// NewLinearMotion creates a new LinearMotion
+func NewLinearMotion(x, y, vx, vy float32) *LinearMotion {
+	return &LinearMotion{Point{x, y}, Point{vx, vy}}
+}
+
+// NewWaypointMotion creates a new WaypointMotion
+func NewWaypointMotion(times []float32, points []Point) *WaypointMotion {
+	return &WaypointMotion{times, points}
+}
+
+// NewMovingObstacle creates a new MovingObstacle
+func NewMovingObstacle(shape Obstacle, motion Motion) *MovingObstacle {
+	return &MovingObstacle{shape, motion}
+}
+
+// ErrWaypoints is the error of a moving obstacle config line whose waypoint
+// motion has no waypoints or times that do not increase
+var ErrWaypoints = errors.New("waypoints need t,x,y values with increasing times")
+
+// Offset of a LinearMotion at time t
+func (m *LinearMotion) Offset(t float32) *Point {
+	return NewPoint(m.Start.X+m.Velocity.X*t, m.Start.Y+m.Velocity.Y*t)
+}
+
+// Offset of a WaypointMotion at time t
+func (m *WaypointMotion) Offset(t float32) *Point {
+	last := len(m.Points) - 1
+	if t <= m.Times[0] {
+		return NewPoint(m.Points[0].X, m.Points[0].Y)
+	}
+	for i := 1; i <= last; i++ {
+		if t <= m.Times[i] {
+			s := (t - m.Times[i-1]) / (m.Times[i] - m.Times[i-1])
+			return NewPoint(m.Points[i-1].X+(m.Points[i].X-m.Points[i-1].X)*s,
+				m.Points[i-1].Y+(m.Points[i].Y-m.Points[i-1].Y)*s)
+		}
+	}
+	return NewPoint(m.Points[last].X, m.Points[last].Y)
+}
+
+// Checks if a point collides with a MovingObstacle at time t
+func (m *MovingObstacle) CollisionAt(pt *Point, t float32) bool {
+	offset := m.motion.Offset(t)
+	return m.shape.Collision(NewPoint(pt.X-offset.X, pt.Y-offset.Y))
+}
+
+// Signed distance from a point to a MovingObstacle at time t
+func (m *MovingObstacle) DistanceAt(pt *Point, t float32) float32 {
+	offset := m.motion.Offset(t)
+	return m.shape.Distance(NewPoint(pt.X-offset.X, pt.Y-offset.Y))
+}
+
+// Checks if the space-time planning mode is enabled
+func (c *ConfigSpace) SpaceTime() bool {
+	return len(c.Moving) > 0 && c.MaxSpeed > 0
+}
+
+// ArrivalTime returns the time a robot moving at maximum speed reaches a
+// milestone, or zero outside of space-time mode
+func (c *ConfigSpace) ArrivalTime(ms *MileStone) float32 {
+	if !c.SpaceTime() {
+		return 0
+	}
+	return ms.Cost / c.MaxSpeed
+}
+
+// Check if a point is free of moving obstacles at time t and keeps the safety
+// margin from them
+func (c *ConfigSpace) FeasibleAt(pt *Point, t float32) bool {
+	for _, m := range c.Moving {
+		if m.CollisionAt(pt, t) || (c.Margin > 0 && m.DistanceAt(pt, t) < c.Margin) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if the motion from pt1 at time t1 to pt2 at time t2 is free of moving
+// obstacles, sampling the segment at the distance field resolution, or half
+// the safety margin if finer
+func (c *ConfigSpace) FeasibleSegmentAt(pt1 *Point, pt2 *Point,
+	t1 float32,
+	t2 float32,
+) bool {
+	step := float32(1)
+	if c.Field != nil {
+		step = c.Field.CellSize
+	}
+	if c.Margin > 0 && c.Margin/2 < step {
+		step = c.Margin / 2
+	}
+	steps := int(math.Ceil(float64(CalcDistance(pt1, pt2) / step)))
+	for s := 0; s <= steps; s++ {
+		frac := float32(1)
+		if steps > 0 {
+			frac = float32(s) / float32(steps)
+		}
+		pt := NewPoint(pt1.X+(pt2.X-pt1.X)*frac, pt1.Y+(pt2.Y-pt1.Y)*frac)
+		if !c.FeasibleAt(pt, t1+(t2-t1)*frac) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if the edge from a milestone to a point reached with a given cost is
+// feasible, in space and (in space-time mode) in time
+func (c *ConfigSpace) FeasibleEdge(from *MileStone, to *Point, cost float32) bool {
+	return c.FeasibleSegment(from.point, to) && c.FeasibleTiming(from, to, cost)
+}
+
+// Check if the edge from a milestone to a point reached with a given cost
+// avoids the moving obstacles, always true outside of space-time mode
+func (c *ConfigSpace) FeasibleTiming(from *MileStone, to *Point, cost float32) bool {
+	if !c.SpaceTime() {
+		return true
+	}
+	return c.FeasibleSegmentAt(from.point, to,
+		c.ArrivalTime(from), cost/c.MaxSpeed)
+}
+
+// Check if a milestone's branch stays free of moving obstacles when its costs
+// are shifted by diff, since a cheaper parent makes the branch arrive earlier
+func (c *ConfigSpace) FeasibleBranch(ms *MileStone, diff float32) bool {
+	if !c.SpaceTime() {
+		return true
+	}
+	feasible := true
+	walkFrom(ms, func(node *MileStone) {
+		if !feasible || node == ms || node.parent == nil {
+			return
+		}
+		feasible = c.FeasibleSegmentAt(node.parent.point, node.point,
+			(node.parent.Cost+diff)/c.MaxSpeed, (node.Cost+diff)/c.MaxSpeed)
+	})
+	return feasible
+}
+
+// Parse a moving obstacle config line. The shape is placed relative to the
+// motion offset: moving,circle,r,motion... or moving,rectangle,w,h,motion...
+// where motion is linear,x,y,vx,vy or waypoints,t,x,y,t,x,y,...
+func parseMovingObstacle(line []string) (*MovingObstacle, error) {
+	var shape Obstacle
+	if line[0] == "rectangle" {
+		shape = parseObstacle([]string{line[0], "0", "0", line[1], line[2]})
+		line = line[3:]
+	} else {
+		shape = parseObstacle([]string{line[0], "0", "0", line[1]})
+		line = line[2:]
+	}
+
+	values := parseFloats(line[1:])
+	if line[0] == "linear" {
+		return NewMovingObstacle(shape,
+			NewLinearMotion(values[0], values[1], values[2], values[3])), nil
+	}
+	if len(values) == 0 || len(values)%3 != 0 {
+		return nil, ErrWaypoints
+	}
+	var times []float32
+	var points []Point
+	for i := 0; i+2 < len(values); i += 3 {
+		if i > 0 && values[i] <= values[i-3] {
+			return nil, ErrWaypoints
+		}
+		times = append(times, values[i])
+		points = append(points, Point{values[i+1], values[i+2]})
+	}
+	return NewMovingObstacle(shape, NewWaypointMotion(times, points)), nil
+}
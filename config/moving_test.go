@@ -0,0 +1,43 @@
+// moving_test.go
+// Christian Jordan
+// Parsing of moving obstacle config lines
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Waypoint motions without waypoints or with times that do not increase are
+// rejected by the config parser
+func TestParseMovingObstacleRejectsBadWaypoints(t *testing.T) {
+	bad := map[string]string{
+		"no waypoints":      "moving,circle,10,waypoints",
+		"partial waypoint":  "moving,circle,10,waypoints,0,100,100,5,200",
+		"equal times":       "moving,circle,10,waypoints,0,100,100,5,200,100,5,300,100",
+		"decreasing times":  "moving,rectangle,10,20,waypoints,5,100,100,0,200,100",
+		"decreasing at end": "moving,circle,10,waypoints,0,100,100,5,200,100,4,300,100",
+	}
+	for name, line := range bad {
+		file := filepath.Join(t.TempDir(), "config.txt")
+		scene := strings.Join([]string{"window,400,400", "delta,10", line}, "\n")
+		if err := os.WriteFile(file, []byte(scene), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewConfigSpace(file); !errors.Is(err, ErrWaypoints) {
+			t.Errorf("%s: got error %v", name, err)
+		}
+	}
+
+	m, err := parseMovingObstacle(strings.Split("circle,10,waypoints,0,100,100,5,200,100", ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt := m.motion.Offset(2.5); pt.X != 150 || pt.Y != 100 {
+		t.Fatalf("offset %v halfway between the waypoints", *pt)
+	}
+}
@@ -0,0 +1,128 @@
+// multiagent.go
+// Christian Jordan
+// Robots sharing a configuration space and inter-robot conflict detection
+
+package config
+
+import (
+	"errors"
+	"math"
+)
+
+// Robot is a start and goal pair planned in a shared configuration space
+type Robot struct {
+	Start *Point // Start point of the robot
+	Goal  *Point // Goal point of the robot
+}
+
+// Conflict is a predicted collision between two robots' timed paths
+type Conflict struct {
+	RobotA int     // Index of the first robot
+	RobotB int     // Index of the second robot
+	Time   float32 // First time the robots are too close
+	Point  *Point  // Position of the first robot at that time
+}
+
+// ForRobot returns a configuration space with the same obstacles as c and a
+// new path plan from start to goal. Moving obstacles are copied so that other
+// robots can be added to the new space without affecting c.
+func (c *ConfigSpace) ForRobot(robot Robot) *ConfigSpace {
+	speed := c.MaxSpeed
+	if speed <= 0 {
+		speed = 1
+	}
+	return &ConfigSpace{
+		Path: NewPathPlan(c.Path.DeltaDist,
+			c.Path.Radius,
+			robot.Goal,
+			robot.Start,
+		),
+		Obstacles:  c.Obstacles,
+		Tree:       c.Tree,
+		Field:      c.Field,
+		Margin:     c.Margin,
+		Moving:     append([]*MovingObstacle(nil), c.Moving...),
+		MaxSpeed:   speed,
+		RobotSize:  c.RobotSize,
+		PruneEvery: c.PruneEvery,
+		WinHeight:  c.WinHeight,
+		WinWidth:   c.WinWidth,
+		ConfigPath: c.ConfigPath,
+	}
+}
+
+// NewRobotObstacle turns a robot's path, driven at a constant speed, into a
+// moving obstacle. The obstacle covers every point within two robot sizes of
+// the robot's center, and rests at the goal after arrival.
+func NewRobotObstacle(path []*Point, speed float32, size float32) *MovingObstacle {
+	times, points := timedPath(path, speed)
+	return NewMovingObstacle(NewCircle(0, 0, 2*size),
+		NewWaypointMotion(times, points))
+}
+
+// ErrConflictStep is the error of a conflict search whose robot speed or step
+// length is not positive
+var ErrConflictStep = errors.New("conflict search needs a positive speed and step")
+
+// Returns the first time two robots' timed paths come within two robot sizes,
+// checked at steps of the given length. The speed and step are positive.
+func findConflict(pathA, pathB []*Point,
+	speed float32,
+	size float32,
+	step float32,
+) (float32, *Point, bool) {
+	timesA, pointsA := timedPath(pathA, speed)
+	timesB, pointsB := timedPath(pathB, speed)
+	motionA := NewWaypointMotion(timesA, pointsA)
+	motionB := NewWaypointMotion(timesB, pointsB)
+
+	// Times are computed from the step count so that they do not drift
+	end := math.Max(float64(timesA[len(timesA)-1]), float64(timesB[len(timesB)-1]))
+	dt := float64(step) / float64(speed)
+	steps := int(math.Ceil(end/dt)) + 1
+	for s := 0; s <= steps; s++ {
+		t := float32(float64(s) * dt)
+		ptA := motionA.Offset(t)
+		if CalcDistance(ptA, motionB.Offset(t)) <= 2*size {
+			return t, ptA, true
+		}
+	}
+	return 0, nil, false
+}
+
+// FindConflicts checks every pair of robot paths for conflicts, at steps of
+// the given length. Robots without a path are skipped.
+func FindConflicts(paths [][]*Point,
+	speed float32,
+	size float32,
+	step float32,
+) ([]Conflict, error) {
+	if !(speed > 0) || !(step > 0) {
+		return nil, ErrConflictStep
+	}
+	var conflicts []Conflict
+	for a := 0; a < len(paths); a++ {
+		for b := a + 1; b < len(paths); b++ {
+			if paths[a] == nil || paths[b] == nil {
+				continue
+			}
+			if t, pt, ok := findConflict(paths[a], paths[b], speed, size, step); ok {
+				conflicts = append(conflicts, Conflict{a, b, t, pt})
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// Returns the arrival time at every point of a path driven at a given speed
+func timedPath(path []*Point, speed float32) ([]float32, []Point) {
+	times := make([]float32, len(path))
+	points := make([]Point, len(path))
+	for i, pt := range path {
+		points[i] = *pt
+		if i > 0 {
+			times[i] = times[i-1] + CalcDistance(path[i-1], pt)/speed
+		}
+	}
+	return times, points
+}
@@ -0,0 +1,42 @@
+// multiagent_test.go
+// Christian Jordan
+// Conflict detection between robot paths
+
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+// A conflict search without a positive speed or step is rejected instead of
+// looping forever
+func TestFindConflictsRejectsZeroStep(t *testing.T) {
+	paths := [][]*Point{
+		{NewPoint(0, 0), NewPoint(100, 0)},
+		{NewPoint(0, 50), NewPoint(100, 50)},
+	}
+	for _, c := range []struct{ speed, step float32 }{{1, 0}, {0, 10}, {-1, 10}} {
+		if _, err := FindConflicts(paths, c.speed, 5, c.step); !errors.Is(err, ErrConflictStep) {
+			t.Errorf("speed %v step %v: got error %v", c.speed, c.step, err)
+		}
+	}
+}
+
+// Robots crossing far from their start meet at the time of the crossing
+func TestFindConflictsLongHorizon(t *testing.T) {
+	paths := [][]*Point{
+		{NewPoint(0, 0), NewPoint(200000, 0)},
+		{NewPoint(150000, -150000), NewPoint(150000, 150000)},
+	}
+	conflicts, err := FindConflicts(paths, 1, 1, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("%d conflicts found", len(conflicts))
+	}
+	if c := conflicts[0]; c.Time < 149998 || c.Time > 150001 {
+		t.Fatalf("conflict at time %v near %v", c.Time, *c.Point)
+	}
+}
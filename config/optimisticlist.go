@@ -125,7 +125,7 @@ func (c *MileStoneChildren) Remove(child *MileStone) bool {
 	prevChild := c.head
 	curChild := c.head
 	for {
-		if child.ParDist == curChild.dist && child == curChild.body {
+		if child == curChild.body {
 			prevChild.lock.Lock()
 			curChild.lock.Lock()
 
@@ -166,7 +166,7 @@ func (c *MileStoneChildren) Contains(child *MileStone) bool {
 	// Create child refs
 	curChild := c.head
 	for {
-		if child.ParDist == curChild.dist && child == curChild.body {
+		if child == curChild.body {
 			// Check if found
 			return true
 
@@ -184,7 +184,7 @@ func (c *MileStoneChildren) Contains(child *MileStone) bool {
 // validate determines whether a child is valid. The function returns
 // true if the feed is valid, otherwise, false.
 func (c *MileStoneChildren) validate(prevChild *child, curChild *child) bool {
-	node := c.head.next
+	node := c.head
 	for {
 		if node == prevChild {
 			return curChild == node.next
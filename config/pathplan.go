@@ -4,13 +4,28 @@
 
 package config
 
+import (
+	"container/heap"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 // PathPlan is a struct used for path planning
 type PathPlan struct {
-	pathHead   *MileStone // Root of the path tree
-	Goal       *MileStone // Goal point
-	distToGoal float32    // Distance to goal
-	Radius     float32    // Visibility radius
-	DeltaDist  float32    // Max distance from branch to new milestone
+	pathHead   *MileStone    // Root of the path tree
+	Goal       *MileStone    // Cheapest reached goal, or the first goal
+	Goals      []*GoalRegion // Candidate goals
+	distToGoal uint32        // Cost of the best reached goal, float32 bits
+	TreeLock   sync.RWMutex  // Read for queries, write for structural changes
+	version    uint64        // Number of structural changes, under the TreeLock
+	Radius     float32       // Visibility radius
+	DeltaDist  float32       // Max distance from branch to new milestone
+	size       int64         // Number of milestones in the tree
+	pruned     int64         // Number of milestones pruned from the tree
+	solved     int32         // Set once the goal has been reached
+	solvedAt   time.Time     // Time the goal was first reached
 }
 
 // Create a new PathPlan
@@ -20,36 +35,124 @@ func NewPathPlan(delta float32,
 	start *Point,
 ) *PathPlan {
 
-	return &PathPlan{
-		pathHead:   NewMileStone(start),
-		Goal:       NewMileStone(goal),
-		distToGoal: 0,
-		Radius:     radius,
-		DeltaDist:  delta,
+	path := &PathPlan{
+		pathHead:  NewMileStone(start),
+		size:      1,
+		Radius:    radius,
+		DeltaDist: delta,
 	}
+	path.Goal = path.AddGoal(goal, nil).Goal
+	return path
 }
 
-// Get the path head
+// Get the cost of the best reached goal, 0 if no goal has been reached
 func (path *PathPlan) GetDistToGoal() float32 {
-	return path.distToGoal
+	return math.Float32frombits(atomic.LoadUint32(&path.distToGoal))
+}
+
+// Record the time the goal is first reached
+func (path *PathPlan) MarkSolved() {
+	if atomic.CompareAndSwapInt32(&path.solved, 0, 1) {
+		path.solvedAt = time.Now()
+	}
+}
+
+// Get the time the goal was first reached, and whether it was reached
+func (path *PathPlan) SolvedAt() (time.Time, bool) {
+	if atomic.LoadInt32(&path.solved) == 0 {
+		return time.Time{}, false
+	}
+	return path.solvedAt, true
+}
+
+// Get the number of milestones in the tree
+func (path *PathPlan) Size() int {
+	return int(atomic.LoadInt64(&path.size))
+}
+
+// Count a milestone added to the tree
+func (path *PathPlan) AddedMileStone() {
+	atomic.AddInt64(&path.size, 1)
+}
+
+// Get the number of structural changes made to the tree. The caller holds
+// the TreeLock.
+func (path *PathPlan) Version() uint64 { return path.version }
+
+// Record a structural change of the tree, the caller holds the write side of
+// the TreeLock
+func (path *PathPlan) Changed() { path.version++ }
+
+// Recount the milestones after the tree was restructured
+func (path *PathPlan) recount() {
+	size := 0
+	path.walk(func(*MileStone) { size++ })
+	atomic.StoreInt64(&path.size, int64(size))
 }
 
-// Get all neighbors in visibilty of a MileStone, assuming MileStone is feasible
+// Get all neighbors in visibilty of a MileStone, assuming MileStone is feasible.
+// The caller holds the read side of the TreeLock.
 func (path *PathPlan) GetNN(newMS *MileStone) NeighborHeap {
 
 	// Find all neighbors of new MileStone within visibility radius
 	var neighborhood NeighborHeap
-	RecurseNN := func(ms *MileStone) {
+	path.walk(func(ms *MileStone) {
+		// Goals are leaves and never become parents
+		if ms.goal {
+			return
+		}
 		dist := CalcDistance(ms.point, newMS.point)
 		if dist <= path.Radius {
 			neighbor := NewNeighborItem(ms, dist)
 			neighborhood.Push(neighbor)
 		}
-	}
-	BranchApply(path.pathHead.children, RecurseNN)
+	})
 
+	// Order the neighborhood so that the nearest neighbor comes first
+	heap.Init(&neighborhood)
 	return neighborhood
 }
 
+// Get the points from the start to the goal, or nil if the goal is unreached
+func (path *PathPlan) GetPath() []*Point {
+	if path.Goal.parent == nil {
+		return nil
+	}
+	var points []*Point
+	for ms := path.Goal; ms != nil; ms = ms.parent {
+		// Region goals share the point of the milestone reaching them
+		if ms.goal && ms.ParDist == 0 {
+			continue
+		}
+		points = append(points, ms.point)
+	}
+	// Reverse so that the path starts at the path head
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	return points
+}
+
+// Apply a function to every milestone in the tree, parents before children
+func (path *PathPlan) walk(f func(*MileStone)) {
+	walkFrom(path.pathHead, f)
+}
+
+// Apply a function to a milestone and its branch, parents before children.
+// Only children whose parent is the visited milestone are followed.
+func walkFrom(root *MileStone, f func(*MileStone)) {
+	stack := []*MileStone{root}
+	for len(stack) > 0 {
+		ms := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		f(ms)
+		for node := ms.children.head.next; node != ms.children.tail; node = node.next {
+			if node.body.parent == ms {
+				stack = append(stack, node.body)
+			}
+		}
+	}
+}
+
 // Draw the path plan
 func (path *PathPlan) Draw() {}
@@ -0,0 +1,88 @@
+// prune.go
+// Christian Jordan
+// Pruning of branches that cannot improve the current solution
+
+package config
+
+import (
+	"container/heap"
+	"sync/atomic"
+)
+
+// Prune removes the branches of the tree whose cost-to-come plus the
+// straight line cost-to-go exceeds the cost of the best reached goal. Such
+// milestones, and all of their descendants, cannot lie on a cheaper path.
+// Goals in a pruned branch become unreached. The caller holds the write side
+// of the TreeLock.
+// Returns the number of milestones pruned.
+func (path *PathPlan) Prune() int {
+	best := path.GetDistToGoal()
+	if best == 0 {
+		return 0
+	}
+
+	// The milestones of the current solution are always kept
+	keep := make(map[*MileStone]bool)
+	for ms := path.Goal; ms != nil; ms = ms.parent {
+		keep[ms] = true
+	}
+
+	// Find the roots of the branches to prune
+	var roots []*MileStone
+	path.walk(func(ms *MileStone) {
+		if ms.pruned || ms.goal || keep[ms] || ms == path.pathHead {
+			return
+		}
+		if ms.parent.pruned {
+			ms.pruned = true
+			return
+		}
+		if ms.Cost+path.CostToGo(ms.point) > best {
+			ms.pruned = true
+			roots = append(roots, ms)
+		}
+	})
+
+	// Detach the branches and release their goals
+	count, removed := 0, 0
+	for _, root := range roots {
+		root.parent.RemoveChild(root)
+		walkFrom(root, func(ms *MileStone) {
+			removed++
+			if ms.goal {
+				ms.parent, ms.Cost, ms.ParDist = nil, 0, 0
+			} else {
+				ms.pruned = true
+				count++
+			}
+		})
+	}
+	if len(roots) > 0 {
+		path.Changed()
+	}
+	atomic.AddInt64(&path.size, -int64(removed))
+	atomic.AddInt64(&path.pruned, int64(count))
+	path.SelectGoal()
+	return count
+}
+
+// Get the total number of milestones pruned from the tree
+func (path *PathPlan) Pruned() int64 {
+	return atomic.LoadInt64(&path.pruned)
+}
+
+// Checks if a milestone was pruned from the tree
+func (ms *MileStone) Pruned() bool { return ms.pruned }
+
+// Get the neighbors that have not been pruned since the neighborhood was
+// found, nearest first, or nil if none is left
+func (h NeighborHeap) Unpruned() NeighborHeap {
+	var unpruned NeighborHeap
+	for _, item := range h {
+		if !item.Neighbor.pruned {
+			unpruned = append(unpruned, item)
+		}
+	}
+	heap.Init(&unpruned)
+	return unpruned
+}
@@ -0,0 +1,338 @@
+// replan.go
+// Christian Jordan
+// Runtime obstacle changes and RRTX-style repair of the path plan tree
+
+package config
+
+import (
+	"container/heap"
+	"math"
+)
+
+// ObstacleEvent is a scheduled change to the obstacles of a configuration space
+type ObstacleEvent struct {
+	Sample   int      // Number of samples after which the event happens
+	Obstacle Obstacle // Obstacle that appears or vanishes
+	Add      bool     // Whether the obstacle appears (true) or vanishes
+}
+
+// Add an obstacle at runtime. Milestones inside the obstacle are removed and
+// branches whose edges cross it are reconnected to the rest of the tree.
+func (c *ConfigSpace) AddObstacle(o Obstacle) {
+	c.Lock.Lock()
+	defer c.Lock.Unlock()
+	c.addObstacle(o)
+}
+
+// Remove an obstacle at runtime. Milestones around the freed area are rewired
+// to take advantage of the new shortcuts. Returns false if o is not found.
+func (c *ConfigSpace) RemoveObstacle(o Obstacle) bool {
+	c.Lock.Lock()
+	defer c.Lock.Unlock()
+	return c.removeObstacle(o)
+}
+
+// Adds an obstacle and repairs the tree. The caller holds the lock.
+func (c *ConfigSpace) addObstacle(o Obstacle) {
+	c.Obstacles = append(c.Obstacles, o)
+	c.rebuild()
+	c.Path.repair(o, c)
+}
+
+// Removes an obstacle and improves the tree. The caller holds the lock.
+func (c *ConfigSpace) removeObstacle(o Obstacle) bool {
+	for i, obstacle := range c.Obstacles {
+		if obstacle == o {
+			c.Obstacles = append(c.Obstacles[:i], c.Obstacles[i+1:]...)
+			c.rebuild()
+			c.Path.improve(o.Bounds(), c)
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyEvents applies all scheduled obstacle events due after a number of
+// samples, returning the number of events applied
+func (c *ConfigSpace) ApplyEvents(sample int) int {
+	applied := 0
+	for c.applyEvent(sample) {
+		applied++
+	}
+	return applied
+}
+
+// Applies the next event if it is due. The event counter advances under the
+// same lock as the change, so a checkpoint sees both or neither.
+func (c *ConfigSpace) applyEvent(sample int) bool {
+	c.Lock.Lock()
+	defer c.Lock.Unlock()
+	if c.nextEvent >= len(c.Events) || c.Events[c.nextEvent].Sample > sample {
+		return false
+	}
+	event := c.Events[c.nextEvent]
+	if event.Add {
+		c.addObstacle(event.Obstacle)
+	} else {
+		c.removeObstacle(event.Obstacle)
+	}
+	c.nextEvent++
+	return true
+}
+
+// Checks if there are scheduled obstacle events left. Only the goroutine
+// applying the events may call it without the lock.
+func (c *ConfigSpace) PendingEvents() bool {
+	return c.nextEvent < len(c.Events)
+}
+
+// Rebuild the obstacle query structures after the obstacles changed
+func (c *ConfigSpace) rebuild() {
+	c.Tree = NewBVH(c.Obstacles)
+	if c.Field != nil {
+		c.Field = NewDistanceField(c.Obstacles,
+			c.WinWidth,
+			c.WinHeight,
+			c.Field.CellSize,
+		)
+	}
+}
+
+// Invalidates milestones and edges blocked by a new obstacle, then reconnects
+// the orphaned milestones in order of their best reachable cost
+func (path *PathPlan) repair(o Obstacle, space *ConfigSpace) {
+	// Find milestones cut off from the path head by the obstacle
+	orphans := make(map[*MileStone]bool)
+	blocked := make(map[*MileStone]bool)
+	var valid []*MileStone
+	path.walk(func(ms *MileStone) {
+		if ms == path.pathHead {
+			valid = append(valid, ms)
+			return
+		}
+		if !space.Feasible(ms.point) && !ms.goal {
+			blocked[ms] = true
+		}
+		if orphans[ms.parent] || blocked[ms] ||
+			o.SegmentCollision(ms.parent.point, ms.point) {
+			orphans[ms] = true
+			return
+		}
+		valid = append(valid, ms)
+	})
+	if len(orphans) == 0 {
+		return
+	}
+
+	// Detach orphans, their branches are rebuilt from scratch
+	for ms := range orphans {
+		if !orphans[ms.parent] {
+			ms.parent.RemoveChild(ms)
+		}
+		ms.parent = nil
+		ms.children = NewChildrenList()
+		ms.Cost = float32(math.Inf(1))
+	}
+	tree := newMileStoneGrid(path.Radius)
+	for _, ms := range valid {
+		if !ms.goal {
+			tree.add(ms)
+		}
+	}
+	lost := newMileStoneGrid(path.Radius)
+	for ms := range orphans {
+		if !blocked[ms] {
+			lost.add(ms)
+		}
+	}
+
+	// Offer every lost milestone its best connection to the valid tree
+	var candidates repairHeap
+	for ms := range orphans {
+		if !blocked[ms] {
+			tree.neighbors(ms.point, func(parent *MileStone, dist float32) {
+				candidates.offer(ms, parent, dist, space)
+			})
+		}
+	}
+
+	// Reconnect cheapest first, each reconnection may help its neighbors
+	for candidates.Len() > 0 {
+		item := heap.Pop(&candidates).(*repairItem)
+		if item.ms.parent != nil {
+			continue
+		}
+		item.ms.parent = item.parent
+		item.ms.ParDist = item.dist
+		item.ms.Cost = item.cost
+		item.parent.SetChild(item.ms)
+		if item.ms.goal {
+			continue
+		}
+		lost.neighbors(item.ms.point, func(ms *MileStone, dist float32) {
+			if ms.parent == nil {
+				candidates.offer(ms, item.ms, dist, space)
+			}
+		})
+	}
+
+	path.recount()
+	path.resetGoals()
+}
+
+// Rewires milestones around a freed area. Milestones near the area are
+// relaxed in order of cost, and each improvement is propagated to neighbors.
+func (path *PathPlan) improve(area AABB, space *ConfigSpace) {
+	grid := newMileStoneGrid(path.Radius)
+	var queue repairHeap
+	path.walk(func(ms *MileStone) {
+		grid.add(ms)
+		if area.Distance(ms.point) <= path.Radius {
+			heap.Push(&queue, &repairItem{ms: ms, cost: ms.Cost})
+		}
+	})
+
+	// Unreached goals may now be connected
+	for _, g := range path.Goals {
+		if !g.Reached() {
+			g.Goal.Cost = float32(math.Inf(1))
+			grid.add(g.Goal)
+		}
+	}
+
+	for queue.Len() > 0 {
+		item := heap.Pop(&queue).(*repairItem)
+		if item.cost != item.ms.Cost {
+			continue
+		}
+		u := item.ms
+		if u.goal {
+			continue
+		}
+		grid.neighbors(u.point, func(v *MileStone, dist float32) {
+			newCost := u.Cost + dist
+			if newCost >= v.Cost || v == path.pathHead || v.isAncestorOf(u) ||
+				!space.FeasibleEdge(u, v.point, newCost) ||
+				(v.parent != nil && !space.FeasibleBranch(v, newCost-v.Cost)) {
+				return
+			}
+			if v.parent != nil {
+				v.parent.RemoveChild(v)
+			}
+			v.parent = u
+			v.ParDist = dist
+			v.propagateCost(newCost - v.Cost)
+			u.SetChild(v)
+			heap.Push(&queue, &repairItem{ms: v, cost: v.Cost})
+		})
+	}
+
+	path.recount()
+	path.resetGoals()
+}
+
+// Resets the cost of unreached goals and selects the cheapest reached goal
+func (path *PathPlan) resetGoals() {
+	for _, g := range path.Goals {
+		if !g.Reached() {
+			g.Goal.Cost = 0
+		} else {
+			path.MarkSolved()
+		}
+	}
+	path.SelectGoal()
+}
+
+// Checks if a milestone is an ancestor of another milestone
+func (ms *MileStone) isAncestorOf(other *MileStone) bool {
+	for node := other; node != nil; node = node.parent {
+		if node == ms {
+			return true
+		}
+	}
+	return false
+}
+
+// Shifts the cost of a milestone and its whole branch by diff. An infinite
+// cost is replaced rather than shifted.
+func (ms *MileStone) propagateCost(diff float32) {
+	if math.IsInf(float64(ms.Cost), 1) {
+		ms.Cost = ms.parent.Cost + ms.ParDist
+		return
+	}
+	walkFrom(ms, func(node *MileStone) {
+		node.Cost += diff
+	})
+}
+
+// repairItem is a candidate connection of a milestone to a new parent
+type repairItem struct {
+	ms     *MileStone // Milestone to connect
+	parent *MileStone // Candidate parent
+	dist   float32    // Distance from the candidate parent
+	cost   float32    // Cost of the milestone through the parent
+}
+
+// repairHeap is a min-heap of candidate connections ordered by cost
+type repairHeap []*repairItem
+
+func (h repairHeap) Len() int            { return len(h) }
+func (h repairHeap) Less(i, j int) bool  { return h[i].cost < h[j].cost }
+func (h repairHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *repairHeap) Push(x interface{}) { *h = append(*h, x.(*repairItem)) }
+
+func (h *repairHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[0 : n-1]
+	return item
+}
+
+// Offers the connection of ms to parent if the edge between them is feasible
+func (h *repairHeap) offer(ms, parent *MileStone, dist float32,
+	space *ConfigSpace,
+) {
+	if space.FeasibleEdge(parent, ms.point, parent.Cost+dist) {
+		heap.Push(h, &repairItem{
+			ms:     ms,
+			parent: parent,
+			dist:   dist,
+			cost:   parent.Cost + dist,
+		})
+	}
+}
+
+// mileStoneGrid is a uniform grid of milestones used for radius queries
+type mileStoneGrid struct {
+	cells  map[[2]int][]*MileStone // Milestones bucketed by cell
+	radius float32                 // Query radius and cell size
+}
+
+func newMileStoneGrid(radius float32) *mileStoneGrid {
+	return &mileStoneGrid{cells: make(map[[2]int][]*MileStone), radius: radius}
+}
+
+func (g *mileStoneGrid) cell(pt *Point) [2]int {
+	return [2]int{int(math.Floor(float64(pt.X / g.radius))),
+		int(math.Floor(float64(pt.Y / g.radius)))}
+}
+
+func (g *mileStoneGrid) add(ms *MileStone) {
+	key := g.cell(ms.point)
+	g.cells[key] = append(g.cells[key], ms)
+}
+
+// Calls f for every milestone within the grid radius of a point
+func (g *mileStoneGrid) neighbors(pt *Point, f func(*MileStone, float32)) {
+	center := g.cell(pt)
+	for i := center[0] - 1; i <= center[0]+1; i++ {
+		for j := center[1] - 1; j <= center[1]+1; j++ {
+			for _, ms := range g.cells[[2]int{i, j}] {
+				if dist := CalcDistance(ms.point, pt); dist <= g.radius && dist > 0 {
+					f(ms, dist)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// sdf.go
+// Christian Jordan
+// Signed distance field used for obstacle clearance queries
+
+package config
+
+import (
+	"math"
+)
+
+// DistanceField is a signed distance field sampled on a regular grid over the
+// configuration space window. Values are negative inside obstacles.
+type DistanceField struct {
+	values   []float32 // Row-major grid of signed distances
+	cols     int       // Number of grid columns (x axis)
+	rows     int       // Number of grid rows (y axis)
+	CellSize float32   // Distance between neighboring grid nodes
+}
+
+// NewDistanceField precomputes the signed distance field of a set of
+// obstacles over a width x height window
+func NewDistanceField(obstacles []Obstacle,
+	width float32,
+	height float32,
+	cellSize float32,
+) *DistanceField {
+	cols := int(math.Ceil(float64(width/cellSize))) + 1
+	rows := int(math.Ceil(float64(height/cellSize))) + 1
+	field := &DistanceField{
+		values:   make([]float32, cols*rows),
+		cols:     cols,
+		rows:     rows,
+		CellSize: cellSize,
+	}
+
+	// Sample the closest obstacle at every grid node
+	tree := NewBVH(obstacles)
+	pt := NewPoint(0, 0)
+	for j := 0; j < rows; j++ {
+		for i := 0; i < cols; i++ {
+			pt.X = float32(i) * cellSize
+			pt.Y = float32(j) * cellSize
+			field.values[j*cols+i] = tree.Distance(pt)
+		}
+	}
+	return field
+}
+
+// Distance returns the bilinearly interpolated signed distance at a point.
+// Points outside of the window are clamped to the window border.
+func (f *DistanceField) Distance(pt *Point) float32 {
+	x := clamp(float64(pt.X/f.CellSize), 0, float64(f.cols-1))
+	y := clamp(float64(pt.Y/f.CellSize), 0, float64(f.rows-1))
+	i := int(math.Min(x, float64(f.cols-2)))
+	j := int(math.Min(y, float64(f.rows-2)))
+	if i < 0 {
+		i = 0
+	}
+	if j < 0 {
+		j = 0
+	}
+	tx := x - float64(i)
+	ty := y - float64(j)
+
+	// Interpolate in float64 to avoid overflow on empty scenes
+	at := func(i, j int) float64 {
+		if i >= f.cols {
+			i = f.cols - 1
+		}
+		if j >= f.rows {
+			j = f.rows - 1
+		}
+		return float64(f.values[j*f.cols+i])
+	}
+	bottom := at(i, j)*(1-tx) + at(i+1, j)*tx
+	top := at(i, j+1)*(1-tx) + at(i+1, j+1)*tx
+	return float32(math.Min(bottom*(1-ty)+top*ty, math.MaxFloat32))
+}
+
+// Clearance returns the signed distance from a point to the closest obstacle,
+// interpolated from the distance field. It may overestimate the exact distance
+// and is meant for statistics, not feasibility checks.
+func (c *ConfigSpace) Clearance(pt *Point) float32 {
+	if c.Field == nil {
+		return c.Tree.Distance(pt)
+	}
+	return c.Field.Distance(pt)
+}
+
+// PathClearance returns the minimum and mean clearance along a path, sampling
+// each segment at the distance field resolution
+func (c *ConfigSpace) PathClearance(path []*Point) (float32, float32) {
+	if len(path) == 0 {
+		return 0, 0
+	}
+	step := float32(1)
+	if c.Field != nil {
+		step = c.Field.CellSize
+	}
+
+	minClear := float32(math.MaxFloat32)
+	var sum float64
+	var count int
+	sample := func(pt *Point) {
+		clear := c.Clearance(pt)
+		if clear < minClear {
+			minClear = clear
+		}
+		sum += float64(clear)
+		count++
+	}
+	for i := 1; i < len(path); i++ {
+		length := CalcDistance(path[i-1], path[i])
+		steps := int(math.Ceil(float64(length / step)))
+		for s := 0; s < steps; s++ {
+			t := float32(s) / float32(steps)
+			sample(NewPoint(path[i-1].X+(path[i].X-path[i-1].X)*t,
+				path[i-1].Y+(path[i].Y-path[i-1].Y)*t))
+		}
+	}
+	sample(path[len(path)-1])
+	return minClear, float32(sum / float64(count))
+}
+
+// SegmentKeepsMargin checks that every point of a segment keeps the safety
+// margin. The exact distance is sampled at steps of at most half the margin.
+// Distance changes no faster than the point moves, so each sample must clear
+// the margin by half a step more.
+func (c *ConfigSpace) SegmentKeepsMargin(pt1, pt2 *Point) bool {
+	if c.Margin <= 0 {
+		return true
+	}
+	length := CalcDistance(pt1, pt2)
+	steps := int(math.Ceil(float64(length / (c.Margin / 2))))
+	slack := float32(0)
+	if steps > 0 {
+		slack = length / float32(steps) / 2
+	}
+	for s := 0; s <= steps; s++ {
+		t := float32(0)
+		if steps > 0 {
+			t = float32(s) / float32(steps)
+		}
+		pt := NewPoint(pt1.X+(pt2.X-pt1.X)*t, pt1.Y+(pt2.Y-pt1.Y)*t)
+		if c.Tree.Distance(pt)-slack < c.Margin {
+			return false
+		}
+	}
+	return true
+}
+
+// Clamps a value between lo and hi
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(v, hi))
+}
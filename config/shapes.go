@@ -4,6 +4,8 @@
 
 package config
 
+import "math"
+
 // Point is a general struct used for points
 type Point struct {
 	X float32
@@ -55,5 +57,53 @@ func (c *Circle) Collision(pt *Point) bool {
 	return false
 }
 
+// Checks if a segment crosses a Rectangle
+func (r *Rectangle) SegmentCollision(a, b *Point) bool {
+	return r.Bounds().IntersectsSegment(a, b)
+}
+
+// Checks if a segment crosses a Circle
+func (c *Circle) SegmentCollision(a, b *Point) bool {
+	return DistanceToSegment(c.pt, a, b) <= c.r
+}
+
+// Bounding box of a Rectangle
+func (r *Rectangle) Bounds() AABB {
+	return NewAABB(r.pt.X, r.pt.Y, r.pt.X+r.w, r.pt.Y+r.h)
+}
+
+// Bounding box of a Circle
+func (c *Circle) Bounds() AABB {
+	return NewAABB(c.pt.X-c.r, c.pt.Y-c.r, c.pt.X+c.r, c.pt.Y+c.r)
+}
+
+// Signed distance from a point to a Rectangle, negative inside
+func (r *Rectangle) Distance(pt *Point) float32 {
+	// Distance from the rectangle center along each axis, minus half extents
+	dx := math.Abs(float64(pt.X-r.pt.X-r.w/2)) - float64(r.w/2)
+	dy := math.Abs(float64(pt.Y-r.pt.Y-r.h/2)) - float64(r.h/2)
+	outside := math.Hypot(math.Max(dx, 0), math.Max(dy, 0))
+	inside := math.Min(math.Max(dx, dy), 0)
+	return float32(outside + inside)
+}
+
+// Signed distance from a point to a Circle, negative inside
+func (c *Circle) Distance(pt *Point) float32 {
+	return CalcDistance(c.pt, pt) - c.r
+}
+
+// Distance from a point to the segment from a to b
+func DistanceToSegment(pt, a, b *Point) float32 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	lengthSq := dx*dx + dy*dy
+	if lengthSq == 0 {
+		return CalcDistance(pt, a)
+	}
+	// Project the point onto the segment and clamp to its ends
+	t := ((pt.X-a.X)*dx + (pt.Y-a.Y)*dy) / lengthSq
+	t = float32(math.Max(0, math.Min(1, float64(t))))
+	return CalcDistance(pt, NewPoint(a.X+t*dx, a.Y+t*dy))
+}
+
 func (r *Rectangle) Draw() {}
 func (c *Circle) Draw()    {}
@@ -0,0 +1,112 @@
+//
+// report.go
+// Christian Jordan
+// Path quality metrics report
+//
+
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"pp_project/config"
+	"pp_project/pathfind"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// Report holds the quality metrics of a planning run
+type Report struct {
+	PathLength     float32 `json:"path_length"`      // Length of the final path
+	Waypoints      int     `json:"waypoints"`        // Number of path waypoints
+	MinClearance   float32 `json:"min_clearance"`    // Closest approach to an obstacle
+	MeanClearance  float32 `json:"mean_clearance"`   // Mean distance to obstacles
+	TotalTurn      float32 `json:"total_turn"`       // Sum of heading changes (radians)
+	MaxCurvature   float32 `json:"max_curvature"`    // Sharpest corner (radians per unit)
+	TreeSize       int     `json:"tree_size"`        // Number of milestones in the tree
+	Pruned         int64   `json:"pruned"`           // Number of milestones pruned
+	Samples        int64   `json:"samples"`          // Number of samples taken
+	FeasibleRatio  float32 `json:"feasible_ratio"`   // Share of feasible samples
+	FirstSolution  float64 `json:"first_solution_s"` // Seconds until the goal was reached
+	SolutionExists bool    `json:"solution_exists"`  // Whether the goal was reached
+}
+
+// NewReport computes the metrics of a run that started at a given time. The
+// path is the final (possibly post-processed) path, nil if the goal was not
+// reached.
+func NewReport(space *config.ConfigSpace,
+	path []*config.Point,
+	start time.Time,
+) *Report {
+	report := &Report{
+		Waypoints: len(path),
+		TreeSize:  space.Path.Size(),
+		Pruned:    space.Path.Pruned(),
+		Samples:   atomic.LoadInt64(&space.Samples),
+	}
+	if report.Samples > 0 {
+		report.FeasibleRatio = float32(atomic.LoadInt64(&space.Feasibles)) /
+			float32(report.Samples)
+	}
+	if solvedAt, ok := space.Path.SolvedAt(); ok {
+		report.SolutionExists = true
+		report.FirstSolution = solvedAt.Sub(start).Seconds()
+	}
+	if path == nil {
+		return report
+	}
+
+	report.PathLength = pathfind.PathLength(path)
+	report.MinClearance, report.MeanClearance = space.PathClearance(path)
+	for i := 1; i < len(path)-1; i++ {
+		turn := pathfind.TurnAngle(path[i-1], path[i], path[i+1])
+		report.TotalTurn += turn
+
+		// Curvature over the mean length of the adjacent segments
+		span := (config.CalcDistance(path[i-1], path[i]) +
+			config.CalcDistance(path[i], path[i+1])) / 2
+		if span > 0 {
+			report.MaxCurvature = float32(math.Max(float64(report.MaxCurvature),
+				float64(turn/span)))
+		}
+	}
+	return report
+}
+
+// WriteTable writes the report as an aligned two column table
+func (r *Report) WriteTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "Path length\t%g\n", r.PathLength)
+	fmt.Fprintf(table, "Waypoints\t%d\n", r.Waypoints)
+	fmt.Fprintf(table, "Clearance (min/mean)\t%g / %g\n", r.MinClearance, r.MeanClearance)
+	fmt.Fprintf(table, "Total turning angle\t%g\n", r.TotalTurn)
+	fmt.Fprintf(table, "Max curvature\t%g\n", r.MaxCurvature)
+	fmt.Fprintf(table, "Tree size\t%d\n", r.TreeSize)
+	fmt.Fprintf(table, "Pruned milestones\t%d\n", r.Pruned)
+	fmt.Fprintf(table, "Samples\t%d\n", r.Samples)
+	fmt.Fprintf(table, "Feasible sample ratio\t%.3f\n", r.FeasibleRatio)
+	if r.SolutionExists {
+		fmt.Fprintf(table, "Time to first solution\t%.3fs\n", r.FirstSolution)
+	} else {
+		fmt.Fprintf(table, "Time to first solution\t-\n")
+	}
+	return table.Flush()
+}
+
+// WriteJSON writes the report as a JSON object
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Write writes the report in a given format, (table) or (json)
+func (r *Report) Write(w io.Writer, format string) error {
+	if format == "json" {
+		return r.WriteJSON(w)
+	}
+	return r.WriteTable(w)
+}
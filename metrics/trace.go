@@ -0,0 +1,96 @@
+//
+// trace.go
+// Christian Jordan
+// Convergence trace of the best cost over iterations and wall time
+//
+
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"pp_project/config"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TracePoint is the state of the planner after a number of samples
+type TracePoint struct {
+	Iteration int     `json:"iteration"` // Number of samples taken
+	Elapsed   float64 `json:"elapsed_s"` // Seconds since the start of the run
+	BestCost  float32 `json:"best_cost"` // Cost of the goal, 0 if unreached
+	TreeSize  int     `json:"tree_size"` // Number of milestones in the tree
+}
+
+// Trace records the convergence of a run, safe for concurrent use
+type Trace struct {
+	Every  int          // Record one sample out of every Every samples
+	start  time.Time    // Start of the run
+	points []TracePoint // Recorded points
+	lock   sync.Mutex   // Lock for recording points
+}
+
+// NewTrace creates a new Trace recording every nth sample of a run
+func NewTrace(every int, start time.Time) *Trace {
+	if every < 1 {
+		every = 1
+	}
+	return &Trace{Every: every, start: start}
+}
+
+// Record stores the state of the configuration space after a given sample
+// if the sample is selected by the subsampling
+func (t *Trace) Record(iteration int, space *config.ConfigSpace) {
+	if iteration%t.Every != 0 {
+		return
+	}
+	point := TracePoint{
+		Iteration: iteration,
+		Elapsed:   time.Since(t.start).Seconds(),
+		BestCost:  space.Path.GetDistToGoal(),
+		TreeSize:  space.Path.Size(),
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.points = append(t.points, point)
+}
+
+// Points returns the recorded points ordered by iteration
+func (t *Trace) Points() []TracePoint {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	points := append([]TracePoint(nil), t.points...)
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Iteration < points[j].Iteration
+	})
+	return points
+}
+
+// WriteFile writes the trace as JSON if the file name ends in .json and as
+// CSV otherwise
+func (t *Trace) WriteFile(filePath string) error {
+	outFile, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	writer := bufio.NewWriter(outFile)
+	if strings.HasSuffix(filePath, ".json") {
+		encoder := json.NewEncoder(writer)
+		if err := encoder.Encode(t.Points()); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(writer, "iteration,elapsed_s,best_cost,tree_size")
+		for _, pt := range t.Points() {
+			fmt.Fprintf(writer, "%d,%g,%g,%d\n",
+				pt.Iteration, pt.Elapsed, pt.BestCost, pt.TreeSize)
+		}
+	}
+	return writer.Flush()
+}
@@ -10,87 +10,186 @@ import (
 	"pp_project/config"
 )
 
-// RRT* algorithm. Assumes samplePt is feasible
+// RRT* algorithm. Assumes samplePt is feasible. The neighborhood is found and
+// the edges of the new MileStone are checked under the read side of the
+// TreeLock, so that samples are planned in parallel. Only the changes to the
+// tree are made under the write side.
 func RRTstar(ms *config.MileStone, space *config.ConfigSpace) float32 {
-	// Find all neighbors of new MileStone within visibility radius
-	neighborhood := space.Path.GetNN(ms)
-
-	// Extend the path from the given point to the nearest point in the tree
-	neighborhood = ExtendPath(neighborhood, space, ms)
-
-	if neighborhood != nil && space.Path.Goal.Cost == 0 && IsGoalVisible(ms, space) {
-		// Prioritize first connection to goal point when visible
-		goalDist := config.CalcDistance(ms.GetPoint(), space.Path.Goal.GetPoint())
-		space.Path.Goal.SetParent(ms, goalDist)
-		ms.SetChild(space.Path.Goal)
-		space.Path.Goal.SetCost(ms.Cost + goalDist)
-
-	} else {
-		// Rewire the tree to account for the new MileStone
-		Rewire(ms, neighborhood, space)
+	path := space.Path
+
+	// Find all neighbors of new MileStone within visibility radius and plan
+	// how it joins the tree
+	path.TreeLock.RLock()
+	ext := PlanExtension(path.GetNN(ms).Unpruned(), space, ms)
+	path.TreeLock.RUnlock()
+	if ext == nil {
+		return path.GetDistToGoal()
+	}
+
+	path.TreeLock.Lock()
+	defer path.TreeLock.Unlock()
+
+	// Extend the path from the new MileStone and rewire its neighbors
+	if !ext.Apply(space) {
+		return path.GetDistToGoal()
 	}
-	return space.Path.Goal.Cost
+
+	// Connect or improve the goals the new MileStone reaches
+	ConnectGoals(ms, space)
+
+	// Rewiring may have lowered the cost of any reached goal
+	path.SelectGoal()
+	return path.GetDistToGoal()
 }
 
-// Extend the path from the given point to the nearest point in the tree
-func ExtendPath(neighborhood config.NeighborHeap, space *config.ConfigSpace,
+// Connect the goals reached by a new MileStone. Region goals connect when the
+// MileStone lands inside them, point goals when they are visible and the edge
+// is feasible. Reached goals are rewired when the new MileStone is a cheaper
+// parent. Returns true if a goal was connected.
+func ConnectGoals(ms *config.MileStone, space *config.ConfigSpace) bool {
+	connected := false
+	for _, g := range space.Path.Goals {
+		if g.Contains(ms) {
+			connected = space.Path.ConnectGoal(g, ms, 0) || connected
+		} else if g.Region == nil && IsGoalVisible(ms, g.Goal, space) {
+			goalDist := config.CalcDistance(ms.GetPoint(), g.Goal.GetPoint())
+			if (!g.Reached() || ms.Cost+goalDist < g.Goal.Cost) &&
+				space.FeasibleEdge(ms, g.Goal.GetPoint(), ms.Cost+goalDist) {
+				connected = space.Path.ConnectGoal(g, ms, goalDist) || connected
+			}
+		}
+	}
+	return connected
+}
+
+// Extension is how a new MileStone joins the tree: its parent among its
+// neighbors, and the neighbors rewired through it
+type Extension struct {
+	ms        *config.MileStone
+	neighbors config.NeighborHeap
+	dists     []float32 // Distance from the new MileStone to each neighbor
+	visible   []bool    // Whether the straight edge to each neighbor is free
+	costs     []float32 // Cost of each neighbor when the plan was made
+	through   []bool    // Whether each neighbor is rewired through the new MileStone
+	parent    int       // Index of the parent among the neighbors
+	version   uint64    // Version of the tree the plan was made for
+}
+
+// Plan how a new MileStone joins the tree. The MileStone is moved toward its
+// nearest neighbor by at most the step size. Returns nil if it cannot join the
+// tree. The caller holds the read side of the TreeLock.
+func PlanExtension(neighborhood config.NeighborHeap, space *config.ConfigSpace,
 	mileStone *config.MileStone,
-) config.NeighborHeap {
-	// Update new MileStone to be a child of nearest neighbor
-	if neighborhood != nil {
-		nearest := neighborhood[0].Neighbor
-
-		// Shorten path to nearest neighbor according to delta
-		mileStone.ShortenPathToNearest(nearest, space.Path.DeltaDist)
-		if !space.Feasible(mileStone.GetPoint()) {
-			return nil
+) *Extension {
+	if len(neighborhood) == 0 {
+		return nil
+	}
+
+	// Shorten path to nearest neighbor according to delta
+	mileStone.ShortenPathToNearest(neighborhood[0].Neighbor, space.Path.DeltaDist)
+	if !space.Feasible(mileStone.GetPoint()) {
+		return nil
+	}
+
+	// Straight edges do not depend on the tree, so they stay valid when the
+	// plan is made again
+	ext := &Extension{
+		ms:        mileStone,
+		neighbors: neighborhood,
+		dists:     make([]float32, len(neighborhood)),
+		visible:   make([]bool, len(neighborhood)),
+		costs:     make([]float32, len(neighborhood)),
+		through:   make([]bool, len(neighborhood)),
+		version:   space.Path.Version(),
+	}
+	for i, nItem := range neighborhood {
+		pt := nItem.Neighbor.GetPoint()
+		ext.dists[i] = config.CalcDistance(mileStone.GetPoint(), pt)
+		ext.visible[i] = space.FeasibleSegment(mileStone.GetPoint(), pt)
+	}
+	if !ext.plan(space) {
+		return nil
+	}
+	return ext
+}
+
+// Choose the cheapest feasible parent of the new MileStone, then the
+// neighbors that become cheaper through it. Returns false if no neighbor can
+// be the parent.
+func (ext *Extension) plan(space *config.ConfigSpace) bool {
+	ms := ext.ms
+	ext.parent = -1
+	var cost float32
+	for i, nItem := range ext.neighbors {
+		n := nItem.Neighbor
+		newCost := n.Cost + ext.dists[i]
+		if !ext.visible[i] || n.Pruned() || (ext.parent >= 0 && newCost >= cost) {
+			continue
 		}
+		if space.FeasibleTiming(n, ms.GetPoint(), newCost) {
+			ext.parent, cost = i, newCost
+		}
+	}
+	if ext.parent < 0 {
+		return false
+	}
 
-		// Set parent and child
-		newDist := config.CalcDistance(nearest.GetPoint(), mileStone.GetPoint())
-		mileStone.SetParent(nearest, newDist)
-		nearest.SetChild(mileStone)
-		mileStone.SetCost(nearest.Cost + newDist)
+	// The new MileStone is not in the tree yet, so its cost is private
+	ms.SetCost(cost)
+	for i, nItem := range ext.neighbors {
+		n := nItem.Neighbor
+		newDistThrough := cost + ext.dists[i]
+		ext.costs[i] = n.Cost
+		ext.through[i] = i != ext.parent && ext.visible[i] && !n.Pruned() &&
+			newDistThrough < n.Cost &&
+			space.FeasibleTiming(ms, n.GetPoint(), newDistThrough) &&
+			space.FeasibleBranch(n, newDistThrough-n.Cost)
 	}
-	return neighborhood
+	return true
 }
 
-// Rewire the tree to account for the new MileStone
-func Rewire(newMileStone *config.MileStone,
-	nHeap config.NeighborHeap,
-	space *config.ConfigSpace,
-) {
-	if nHeap == nil {
-		return
+// Apply adds the new MileStone to the tree and rewires its neighbors. The plan
+// is made again if the tree changed since it was made. Returns false if the
+// MileStone cannot join the tree. The caller holds the write side of the
+// TreeLock.
+func (ext *Extension) Apply(space *config.ConfigSpace) bool {
+	if ext.version != space.Path.Version() && !ext.plan(space) {
+		return false
 	}
-	for _, nItem := range nHeap {
-		// Calc distance between newMileStone and neighbor
-		distBetween := config.CalcDistance(newMileStone.GetPoint(),
-			nItem.Neighbor.GetPoint())
-
-		// Calc distances passing through the newMileStone
-		newDistThrough := newMileStone.Cost + distBetween
-		if newDistThrough < nItem.Neighbor.Cost {
-			nItem.Neighbor.SetParent(newMileStone, distBetween)
-			nItem.Neighbor.RemoveChild(newMileStone)
-			newMileStone.SetChild(nItem.Neighbor)
-			nItem.Neighbor.UpdateCost(newDistThrough - nItem.Neighbor.Cost)
+	space.Path.Changed()
+
+	// Set parent and child
+	ms := ext.ms
+	parent := ext.neighbors[ext.parent].Neighbor
+	ms.SetParent(parent, ext.dists[ext.parent])
+	parent.SetChild(ms)
+	space.Path.AddedMileStone()
+	ms.SetCost(parent.Cost + ext.dists[ext.parent])
+
+	// Rewire the neighbors that are cheaper through the new MileStone
+	for i, nItem := range ext.neighbors {
+		if !ext.through[i] {
 			continue
 		}
+		n := nItem.Neighbor
+		newDistThrough := ms.Cost + ext.dists[i]
 
-		// Calc distances passing to the newMileStone
-		newDistTo := nItem.Neighbor.Cost + distBetween
-		if newDistTo < newMileStone.Cost {
-			newMileStone.SetParent(nItem.Neighbor, distBetween)
-			newMileStone.RemoveChild(nItem.Neighbor)
-			nItem.Neighbor.SetChild(newMileStone)
-			newMileStone.UpdateCost(newDistTo - newMileStone.Cost)
+		// An earlier rewire may have lowered the cost of the neighbor's branch
+		if newDistThrough >= n.Cost || (n.Cost != ext.costs[i] &&
+			!space.FeasibleBranch(n, newDistThrough-n.Cost)) {
 			continue
 		}
+		n.SetParent(ms, ext.dists[i])
+		ms.SetChild(n)
+		n.UpdateCost(newDistThrough - n.Cost)
 	}
+	return true
 }
 
-func IsGoalVisible(ms *config.MileStone, space *config.ConfigSpace) bool {
-	return config.CalcDistance(ms.GetPoint(),
-		space.Path.Goal.GetPoint()) <= space.Path.Radius
+// Checks if a goal is within the visibility radius of a MileStone
+func IsGoalVisible(ms *config.MileStone,
+	goal *config.MileStone,
+	space *config.ConfigSpace,
+) bool {
+	return config.CalcDistance(ms.GetPoint(), goal.GetPoint()) <= space.Path.Radius
 }
@@ -0,0 +1,74 @@
+// RRTstar_test.go
+// Christian Jordan
+// Safety margin along the edges of a planned tree
+
+package pathfind
+
+import (
+	"os"
+	"path/filepath"
+	"pp_project/config"
+	"strings"
+	"testing"
+)
+
+// Three rectangles with narrow passages between them
+var marginScene = []string{
+	"window,400,400",
+	"radius,30",
+	"delta,15",
+	"margin,6",
+	"start,20,20",
+	"goal,380,380",
+	"rectangle,100,0,40,300",
+	"rectangle,180,100,40,300",
+	"rectangle,260,0,40,300",
+}
+
+// Every edge of the tree, including the edges to the goals, keeps the safety
+// margin along its whole length and not only at its milestones
+func TestEdgesKeepMargin(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.txt")
+	if err := os.WriteFile(file, []byte(strings.Join(marginScene, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	space, err := config.NewConfigSpace(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Rand = NewRandom(1)
+
+	var milestones []*config.MileStone
+	for i := 0; i < 5000; i++ {
+		point := SamplePoint(space)
+		if space.Feasible(point) {
+			ms := config.NewMileStone(point)
+			RRTstar(ms, space)
+			milestones = append(milestones, ms)
+		}
+	}
+	for _, g := range space.Path.Goals {
+		milestones = append(milestones, g.Goal)
+	}
+
+	edges := 0
+	for _, ms := range milestones {
+		parent := ms.GetParent()
+		if parent == nil {
+			continue
+		}
+		edges++
+		a, b := parent.GetPoint(), ms.GetPoint()
+		steps := int(config.CalcDistance(a, b)/0.05) + 1
+		for s := 0; s <= steps; s++ {
+			f := float32(s) / float32(steps)
+			pt := config.NewPoint(a.X+(b.X-a.X)*f, a.Y+(b.Y-a.Y)*f)
+			if d := space.Tree.Distance(pt); d < space.Margin {
+				t.Fatalf("edge %v %v comes within %v of an obstacle", *a, *b, d)
+			}
+		}
+	}
+	if edges == 0 {
+		t.Fatal("no edges planned")
+	}
+}
@@ -5,13 +5,47 @@
 package pathfind
 
 import (
-	"math/rand"
 	"pp_project/config"
+	"sync/atomic"
+	"time"
 )
 
+// Random is a splitmix64 random number generator whose state is a single
+// counter, so it is safe for concurrent use and can be saved and restored
+type Random struct {
+	state uint64 // Counter advanced on every draw
+}
+
+// Rand is the random number generator used for sampling
+var Rand = NewRandom(uint64(time.Now().UnixNano()))
+
+// NewRandom creates a new Random from a seed
+func NewRandom(seed uint64) *Random {
+	return &Random{state: seed}
+}
+
+// Uint64 returns a pseudo-random 64-bit value
+func (r *Random) Uint64() uint64 {
+	z := atomic.AddUint64(&r.state, 0x9e3779b97f4a7c15)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Float32 returns a pseudo-random number in [0,1)
+func (r *Random) Float32() float32 {
+	return float32(r.Uint64()>>40) / (1 << 24)
+}
+
+// State returns the state of the generator
+func (r *Random) State() uint64 { return atomic.LoadUint64(&r.state) }
+
+// SetState restores a state returned by State
+func (r *Random) SetState(state uint64) { atomic.StoreUint64(&r.state, state) }
+
 // SamplePoint samples a random point in the configuration space
 func SamplePoint(space *config.ConfigSpace) *config.Point {
-	randX := rand.Float32() * float32(space.WinWidth)
-	randY := rand.Float32() * float32(space.WinHeight)
+	randX := Rand.Float32() * float32(space.WinWidth)
+	randY := Rand.Float32() * float32(space.WinHeight)
 	return config.NewPoint(randX, randY)
 }
@@ -0,0 +1,156 @@
+// smoothing.go
+// Christian Jordan
+// Path shortcutting and smoothing post-processing
+
+package pathfind
+
+import (
+	"math/rand"
+	"pp_project/config"
+)
+
+// Number of times failing curve spans are refined before giving up
+const smoothRefinements = 6
+
+// PathLength returns the total length of a path
+func PathLength(path []*config.Point) float32 {
+	var length float32
+	for i := 1; i < len(path); i++ {
+		length += config.CalcDistance(path[i-1], path[i])
+	}
+	return length
+}
+
+// Shortcut randomly picks pairs of waypoints and removes the waypoints between
+// them whenever the straight segment joining them is clear
+func Shortcut(path []*config.Point,
+	space *config.ConfigSpace,
+	iterations int,
+) []*config.Point {
+	path = append([]*config.Point(nil), path...)
+	for it := 0; it < iterations && len(path) > 2; it++ {
+		i := rand.Intn(len(path) - 2)
+		j := i + 2 + rand.Intn(len(path)-i-2)
+		if !space.FeasibleSegment(path[i], path[j]) {
+			continue
+		}
+		candidate := append(append([]*config.Point(nil), path[:i+1]...), path[j:]...)
+		if PathOnTime(candidate, space) {
+			path = candidate
+		}
+	}
+	return path
+}
+
+// SmoothPath fits a uniform cubic B-spline through the path, using its
+// waypoints as control points. Curve spans that lose clearance are pulled
+// towards the original path by refining their control points. The original
+// path is returned if the curve cannot be made clear.
+func SmoothPath(path []*config.Point,
+	space *config.ConfigSpace,
+	samples int,
+) []*config.Point {
+	if len(path) < 3 {
+		return path
+	}
+	control := path
+	for round := 0; round <= smoothRefinements; round++ {
+		curve, failed := bSpline(control, space, samples)
+		if len(failed) == 0 {
+			if PathOnTime(curve, space) {
+				return curve
+			}
+			return path
+		}
+		control = refine(control, failed)
+	}
+	return path
+}
+
+// PathOnTime checks a path driven at maximum speed against the moving
+// obstacles, always true outside of space-time mode
+func PathOnTime(path []*config.Point, space *config.ConfigSpace) bool {
+	if !space.SpaceTime() {
+		return true
+	}
+	var t float32
+	for i := 1; i < len(path); i++ {
+		dt := config.CalcDistance(path[i-1], path[i]) / space.MaxSpeed
+		if !space.FeasibleSegmentAt(path[i-1], path[i], t, t+dt) {
+			return false
+		}
+		t += dt
+	}
+	return true
+}
+
+// Samples a clamped uniform cubic B-spline of the control points. Returns the
+// curve and the control point indices of the spans that are not clear.
+func bSpline(control []*config.Point,
+	space *config.ConfigSpace,
+	samples int,
+) ([]*config.Point, map[int]bool) {
+	// Repeat the end points so that the curve starts and ends on them
+	n := len(control)
+	at := func(q int) *config.Point {
+		if q < 2 {
+			return control[0]
+		} else if q-2 >= n {
+			return control[n-1]
+		}
+		return control[q-2]
+	}
+
+	curve := []*config.Point{control[0]}
+	failed := make(map[int]bool)
+	for k := 0; k < n+1; k++ {
+		p0, p1, p2, p3 := at(k), at(k+1), at(k+2), at(k+3)
+		clear := true
+		for s := 1; s <= samples; s++ {
+			t := float32(s) / float32(samples)
+			b0 := (1 - t) * (1 - t) * (1 - t) / 6
+			b1 := (3*t*t*t - 6*t*t + 4) / 6
+			b2 := (-3*t*t*t + 3*t*t + 3*t + 1) / 6
+			b3 := t * t * t / 6
+			pt := config.NewPoint(b0*p0.X+b1*p1.X+b2*p2.X+b3*p3.X,
+				b0*p0.Y+b1*p1.Y+b2*p2.Y+b3*p3.Y)
+			if clear && !space.FeasibleSegment(curve[len(curve)-1], pt) {
+				clear = false
+			}
+			curve = append(curve, pt)
+		}
+		if !clear {
+			// Record the control points shaping this span
+			for q := k; q <= k+3; q++ {
+				failed[clamp(q-2, 0, n-1)] = true
+			}
+		}
+	}
+	// Avoid rounding errors on the end point
+	curve[len(curve)-1] = control[n-1]
+	return curve, failed
+}
+
+// Inserts midpoints on the control segments between failed control points,
+// which pulls the curve closer to the control polygon
+func refine(control []*config.Point, failed map[int]bool) []*config.Point {
+	refined := []*config.Point{control[0]}
+	for i := 1; i < len(control); i++ {
+		if failed[i-1] && failed[i] {
+			refined = append(refined, config.NewPoint(
+				(control[i-1].X+control[i].X)/2,
+				(control[i-1].Y+control[i].Y)/2))
+		}
+		refined = append(refined, control[i])
+	}
+	return refined
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	} else if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,198 @@
+// trajectory.go
+// Christian Jordan
+// Velocity and time parameterisation of planned paths
+
+package pathfind
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"pp_project/config"
+)
+
+// TrajectoryPoint is the timed state of a robot following a trajectory
+type TrajectoryPoint struct {
+	T  float32 // Time
+	X  float32 // Position x
+	Y  float32 // Position y
+	VX float32 // Velocity x
+	VY float32 // Velocity y
+}
+
+// Trajectory is a time-parameterised path sampled at a fixed time step
+type Trajectory []TrajectoryPoint
+
+// ErrTrajectoryLimits is returned for a velocity, acceleration or time step
+// that is not a positive finite number
+var ErrTrajectoryLimits = errors.New(
+	"trajectory velocity, acceleration and time step must be positive")
+
+// NewTrajectory time-parameterises a path with a trapezoidal velocity profile
+// on every segment. The speed at each waypoint is limited by the curvature of
+// the corner so that the lateral acceleration stays below maxAcc, and by how
+// fast the robot can speed up or brake along the path. The robot starts and
+// ends at rest.
+func NewTrajectory(path []*config.Point,
+	maxVel float32,
+	maxAcc float32,
+	dt float32,
+) (Trajectory, error) {
+	if !positive(maxVel) || !positive(maxAcc) || !positive(dt) {
+		return nil, ErrTrajectoryLimits
+	}
+	if len(path) == 0 {
+		return nil, nil
+	}
+	speeds := waypointSpeeds(path, float64(maxVel), float64(maxAcc))
+
+	// Sample every segment at the global time step
+	traj := Trajectory{{T: 0, X: path[0].X, Y: path[0].Y}}
+	var start float64 // Time at the start of the segment
+	next := float64(dt)
+	for i := 1; i < len(path); i++ {
+		length := float64(config.CalcDistance(path[i-1], path[i]))
+		if length == 0 {
+			continue
+		}
+		profile := newSegmentProfile(length, speeds[i-1], speeds[i],
+			float64(maxVel), float64(maxAcc))
+		dirX := float64(path[i].X-path[i-1].X) / length
+		dirY := float64(path[i].Y-path[i-1].Y) / length
+		for ; next <= start+profile.duration(); next += float64(dt) {
+			s, v := profile.at(next - start)
+			traj = append(traj, TrajectoryPoint{
+				T:  float32(next),
+				X:  path[i-1].X + float32(dirX*s),
+				Y:  path[i-1].Y + float32(dirY*s),
+				VX: float32(dirX * v),
+				VY: float32(dirY * v),
+			})
+		}
+		start += profile.duration()
+	}
+
+	// Finish at rest on the last waypoint
+	last := path[len(path)-1]
+	traj = append(traj, TrajectoryPoint{T: float32(start), X: last.X, Y: last.Y})
+	return traj, nil
+}
+
+// Checks that a value is a positive finite number
+func positive(v float32) bool {
+	return v > 0 && !math.IsInf(float64(v), 1)
+}
+
+// Duration returns the time needed to follow the trajectory
+func (traj Trajectory) Duration() float32 {
+	if len(traj) == 0 {
+		return 0
+	}
+	return traj[len(traj)-1].T
+}
+
+// WriteCSV writes the trajectory as t,x,y,vx,vy rows with a header
+func (traj Trajectory) WriteCSV(filePath string) error {
+	outFile, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	writer := bufio.NewWriter(outFile)
+	fmt.Fprintln(writer, "t,x,y,vx,vy")
+	for _, pt := range traj {
+		fmt.Fprintf(writer, "%g,%g,%g,%g,%g\n", pt.T, pt.X, pt.Y, pt.VX, pt.VY)
+	}
+	return writer.Flush()
+}
+
+// Returns the highest reachable speed at every waypoint of a path
+func waypointSpeeds(path []*config.Point, maxVel, maxAcc float64) []float64 {
+	n := len(path)
+	speeds := make([]float64, n)
+
+	// Limit corner speeds by curvature, estimated from the turning angle over
+	// the mean length of the adjacent segments
+	for i := 1; i < n-1; i++ {
+		speeds[i] = maxVel
+		in := config.CalcDistance(path[i-1], path[i])
+		out := config.CalcDistance(path[i], path[i+1])
+		if in == 0 || out == 0 {
+			continue
+		}
+		curvature := TurnAngle(path[i-1], path[i], path[i+1]) / ((in + out) / 2)
+		if curvature > 0 {
+			speeds[i] = math.Min(maxVel, math.Sqrt(maxAcc/float64(curvature)))
+		}
+	}
+
+	// Forward pass limits acceleration, backward pass limits braking
+	for i := 1; i < n; i++ {
+		length := float64(config.CalcDistance(path[i-1], path[i]))
+		speeds[i] = math.Min(speeds[i],
+			math.Sqrt(speeds[i-1]*speeds[i-1]+2*maxAcc*length))
+	}
+	for i := n - 2; i >= 0; i-- {
+		length := float64(config.CalcDistance(path[i], path[i+1]))
+		speeds[i] = math.Min(speeds[i],
+			math.Sqrt(speeds[i+1]*speeds[i+1]+2*maxAcc*length))
+	}
+	return speeds
+}
+
+// TurnAngle returns the absolute heading change at b on the path a, b, c
+func TurnAngle(a, b, c *config.Point) float32 {
+	in := math.Atan2(float64(b.Y-a.Y), float64(b.X-a.X))
+	out := math.Atan2(float64(c.Y-b.Y), float64(c.X-b.X))
+	turn := math.Abs(out - in)
+	if turn > math.Pi {
+		turn = 2*math.Pi - turn
+	}
+	return float32(turn)
+}
+
+// segmentProfile is a trapezoidal velocity profile over one path segment
+type segmentProfile struct {
+	v0, v1, peak float64 // Start, end and peak speeds
+	acc          float64 // Acceleration limit
+	tAcc         float64 // Time spent speeding up
+	tCruise      float64 // Time spent at peak speed
+	tDec         float64 // Time spent braking
+}
+
+// Creates the fastest profile covering length from speed v0 to speed v1
+func newSegmentProfile(length, v0, v1, maxVel, maxAcc float64) *segmentProfile {
+	// Peak where the acceleration and braking ramps meet, capped at maxVel
+	peak := math.Min(maxVel, math.Sqrt(maxAcc*length+(v0*v0+v1*v1)/2))
+	peak = math.Max(peak, math.Max(v0, v1))
+	dAcc := (peak*peak - v0*v0) / (2 * maxAcc)
+	dDec := (peak*peak - v1*v1) / (2 * maxAcc)
+	return &segmentProfile{
+		v0:      v0,
+		v1:      v1,
+		peak:    peak,
+		acc:     maxAcc,
+		tAcc:    (peak - v0) / maxAcc,
+		tCruise: math.Max(length-dAcc-dDec, 0) / peak,
+		tDec:    (peak - v1) / maxAcc,
+	}
+}
+
+func (p *segmentProfile) duration() float64 { return p.tAcc + p.tCruise + p.tDec }
+
+// Returns the distance travelled and the speed at time t into the segment
+func (p *segmentProfile) at(t float64) (float64, float64) {
+	if t <= p.tAcc {
+		return p.v0*t + p.acc*t*t/2, p.v0 + p.acc*t
+	}
+	s := p.v0*p.tAcc + p.acc*p.tAcc*p.tAcc/2
+	if t <= p.tAcc+p.tCruise {
+		return s + p.peak*(t-p.tAcc), p.peak
+	}
+	s += p.peak * p.tCruise
+	t -= p.tAcc + p.tCruise
+	return s + p.peak*t - p.acc*t*t/2, p.peak - p.acc*t
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pp_project/concurrent"
+	"pp_project/config"
+	"pp_project/pathfind"
+)
+
+// Checkpointer writes a checkpoint of a run every number of samples
+type Checkpointer struct {
+	File  string // Checkpoint file
+	Every int    // Write a checkpoint every this many samples, 0 only at the end
+}
+
+// Record writes a checkpoint if the sample is selected. It is called by a
+// running task, which holds the config space lock for reading, so the
+// TreeLock is taken to keep the tree from changing.
+func (c *Checkpointer) Record(iteration int, space *config.ConfigSpace) {
+	if c.Every <= 0 || iteration%c.Every != 0 {
+		return
+	}
+	space.Path.TreeLock.Lock()
+	defer space.Path.TreeLock.Unlock()
+	if err := c.Write(space); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not write checkpoint: ", err)
+	}
+}
+
+// Write a checkpoint of the config space and the sampling random number
+// generator. The checkpoint replaces the previous one only once complete.
+func (c *Checkpointer) Write(space *config.ConfigSpace) error {
+	tmp := c.File + ".tmp"
+	if err := space.WriteCheckpoint(tmp, pathfind.Rand.State()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.File)
+}
+
+// Resume a run from a checkpoint file
+func Resume(space *config.ConfigSpace, filePath string) error {
+	progress, err := space.ReadCheckpoint(filePath)
+	if err != nil {
+		return err
+	}
+	pathfind.Rand.SetState(progress.RandState)
+	return nil
+}
+
+// Recorders records to several recorders in order
+type Recorders []concurrent.Recorder
+
+// Record the state of a config space to every recorder
+func (r Recorders) Record(iteration int, space *config.ConfigSpace) {
+	for _, recorder := range r {
+		recorder.Record(iteration, space)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"pp_project/config"
+)
+
+// RunMultiAgent plans every robot of the configuration space in priority
+// order. Each robot treats the timed paths of the robots planned before it as
+// moving obstacles. Returns the configuration space of every robot and the
+// conflicts left between the planned paths, or an error if the conflicts
+// cannot be checked.
+func RunMultiAgent(configSpace *config.ConfigSpace,
+	sample_size int,
+	threads int,
+	strategy string,
+) ([]*config.ConfigSpace, []config.Conflict, error) {
+	var spaces []*config.ConfigSpace
+	var paths [][]*config.Point
+
+	for _, robot := range configSpace.Robots {
+		space := configSpace.ForRobot(robot)
+		for _, path := range paths {
+			if path != nil {
+				space.Moving = append(space.Moving,
+					config.NewRobotObstacle(path, space.MaxSpeed, space.RobotSize))
+			}
+		}
+
+		// Plan the robot
+		if threads == 1 {
+			RunSequential(space, sample_size, nil, nil)
+		} else {
+			RunParallel(space, sample_size, threads, strategy, nil, nil)
+		}
+		spaces = append(spaces, space)
+		paths = append(paths, space.Path.GetPath())
+	}
+
+	// Report conflicts the prioritised planning could not resolve
+	conflicts, err := config.FindConflicts(paths,
+		spaces[0].MaxSpeed,
+		spaces[0].RobotSize,
+		spaces[0].Path.DeltaDist,
+	)
+	return spaces, conflicts, err
+}
@@ -1,21 +1,44 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"pp_project/concurrent"
 	"pp_project/config"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// RunParallel runs the pathfinding algorithm in parallel
-func RunParallel(input string,
+// RunParallel runs the pathfinding algorithm in parallel, returning the futures
+// of the samples, only the last one with a bounded queue, and the telemetry of
+// the executor
+func RunParallel(configSpace *config.ConfigSpace,
 	sample_size int,
 	threads int,
 	strategy string,
-) []concurrent.Future {
+	trace concurrent.Recorder,
+	stop *StopCriteria,
+) ([]concurrent.Future, concurrent.Stats) {
 	var executor concurrent.ExecutorService
 	var progress []concurrent.Future
 
-	// Read the configuration space from the input file
-	configSpace := config.NewConfigSpace(input)
+	// Pending tasks are dropped as soon as the run stops
+	var options []concurrent.Option
+	if stop != nil {
+		options = append(options, concurrent.WithContext(stop.Context()))
+	}
+	if *queueBound > 0 {
+		options = append(options,
+			concurrent.WithBoundedQueue(*queueBound, concurrent.BlockWhenFull))
+	}
+	if *deque == "chaselev" {
+		options = append(options, concurrent.WithDeque(func() concurrent.DEQueue {
+			return concurrent.NewChaseLevDeque()
+		}))
+	}
+
+	options = append(options, concurrent.WithStealOptions(stealOptions()))
 
 	if strategy == "wb" {
 		// Run the work balancing executor
@@ -23,21 +46,96 @@ func RunParallel(input string,
 			threads,
 			sample_size/(threads),
 			sample_size/(threads*threads),
+			options...,
 		)
 	} else if strategy == "ws" {
-		// Run the work stealing executor
+		// Run the work stealing executor, which steals batches of the
+		// threshold by default
 		executor = concurrent.NewWorkStealingExecutor(
 			threads,
-			sample_size/(threads),
+			*stealBatch,
+			options...,
 		)
 	}
 
-	for i := 0; i < sample_size; i++ {
-		task := concurrent.NewUpdateTask(configSpace)
+	// Apply obstacle changes while the executor keeps sampling
+	done := make(chan interface{})
+	var events sync.WaitGroup
+	if configSpace.PendingEvents() {
+		events.Add(1)
+		go func() {
+			defer events.Done()
+			RunEvents(configSpace, done)
+		}()
+	}
+
+	// A resumed run continues from the samples already taken
+	for i := int(configSpace.Samples); i < sample_size; i++ {
+		if stop != nil && stop.Done(configSpace) {
+			break
+		}
+		// Study scaling within one run
+		if *resizeTo > 0 && i == sample_size/2 {
+			if err := executor.Resize(*resizeTo); err != nil {
+				fmt.Fprintln(os.Stderr, "Resize:", err)
+			}
+		}
+		var task *concurrent.UpdateTask
+		if stop != nil {
+			// Tasks started after the run should stop take no sample
+			task = concurrent.NewAnytimeUpdateTask(configSpace, trace, stop)
+		} else {
+			task = concurrent.NewTracedUpdateTask(configSpace, trace)
+		}
 		f := executor.Submit(task)
-		progress = append(progress, f)
+		if *queueBound > 0 && len(progress) > 0 {
+			// A bounded run keeps only the last future so memory stays flat
+			progress[0] = f
+		} else {
+			progress = append(progress, f)
+		}
 	}
 	executor.Shutdown() // Shutdown the executor
 
-	return progress
+	// An event being applied may still rebuild the tree
+	close(done)
+	events.Wait()
+
+	return progress, executor.Stats()
+}
+
+// Builds the steal options of the work stealing executor from the flags. The
+// batch policy and random victims are the defaults of the executor.
+func stealOptions() concurrent.StealOptions {
+	var opts concurrent.StealOptions
+	switch *steal {
+	case "one":
+		opts.Policy = concurrent.StealOne{}
+	case "half":
+		opts.Policy = concurrent.StealHalf{}
+	case "adaptive":
+		opts.Policy = concurrent.AdaptiveSteal{Min: 2, Max: int32(*stealBatch)}
+	}
+	switch *victim {
+	case "roundrobin":
+		opts.Victims = concurrent.NewRoundRobinVictim
+	case "last":
+		opts.Victims = concurrent.NewLastSuccessfulVictim
+	}
+	return opts
+}
+
+// RunEvents applies scheduled obstacle events as the sample count advances,
+// until all events are applied or done is closed
+func RunEvents(configSpace *config.ConfigSpace, done chan interface{}) {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for configSpace.PendingEvents() {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			configSpace.ApplyEvents(int(atomic.LoadInt64(&configSpace.Samples)))
+		}
+	}
 }
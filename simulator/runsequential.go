@@ -6,16 +6,24 @@ import (
 )
 
 // RunSequential runs the pathfinding algorithm sequentially
-func RunSequential(input string,
+func RunSequential(configSpace *config.ConfigSpace,
 	sample_size int,
+	trace concurrent.Recorder,
+	stop *StopCriteria,
 ) []float32 {
 	var progress []float32
 
-	// Read the configuration space from the input file
-	configSpace := config.NewConfigSpace(input)
+	// A resumed run continues from the samples already taken
+	for i := int(configSpace.Samples); i < sample_size; i++ {
+		// Keep the best path found so far once the run should stop
+		if stop != nil && stop.Done(configSpace) {
+			break
+		}
 
-	for i := 0; i < sample_size; i++ {
-		task := concurrent.NewUpdateTask(configSpace)
+		// Apply obstacle changes scheduled for this sample
+		configSpace.ApplyEvents(i)
+
+		task := concurrent.NewTracedUpdateTask(configSpace, trace)
 		task.Run()
 		progress = append(progress, task.GetDistToGoal())
 	}
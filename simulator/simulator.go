@@ -1,73 +1,270 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"pp_project/concurrent"
+	"pp_project/config"
+	"pp_project/metrics"
+	"pp_project/pathfind"
 	"strconv"
 	"time"
 )
 
-const usage = "Usage: simulator [mode] [sample_size] input_file [parallelization] [number of threads] \n" +
+const usage = "Usage: simulator [options] [mode] [sample_size] input_file [parallelization] [number of threads] \n" +
 	"[mode] = (b) run benchmark mode, (d) run image draw mode\n" +
 	"[sample_size] = The number of samples to be generated\n" +
 	"input_file = The file used to set up the configuration space\n" +
 	"[parallelization] = (wb) work balancing, (ws) work stealing\n" +
 	"[number of threads] = Runs parallel version of the program with the specified number of threads,\n" +
-	"                      if not specified, runs the sequential version of the program.\n"
+	"                      if not specified, runs the sequential version of the program.\n" +
+	"[options] =\n"
+
+// Command line options
+var (
+	shortcut = flag.Int("shortcut", 0,
+		"Number of random shortcutting attempts on the final path")
+	smooth = flag.Int("smooth", 0,
+		"Samples per B-spline span when smoothing the final path, 0 disables")
+	trajectory = flag.String("trajectory", "",
+		"Write the time-parameterised final path to this CSV file")
+	maxVel   = flag.Float64("vmax", 1, "Maximum velocity of the trajectory")
+	maxAcc   = flag.Float64("amax", 1, "Maximum acceleration of the trajectory")
+	timeStep = flag.Float64("dt", 0.1, "Time step of the trajectory")
+	report   = flag.String("report", "table",
+		"Format of the run report, (table), (json) or (none)")
+	traceFile = flag.String("trace", "",
+		"Write the convergence trace to this CSV (or .json) file")
+	traceEvery = flag.Int("trace-every", 100,
+		"Record the convergence trace every this many samples")
+	prune = flag.Int("prune", 0,
+		"Prune branches that cannot improve the solution every this many samples")
+	deadline = flag.Duration("deadline", 0,
+		"Stop sampling after this long (e.g. 30s) and keep the best path")
+	threshold = flag.Float64("cost", 0,
+		"Stop sampling once the best cost is at most this, 0 disables")
+	stall = flag.Int("stall", 0,
+		"Stop sampling after this many samples without improvement, 0 disables")
+	checkpoint = flag.String("checkpoint", "",
+		"Write a checkpoint of the run to this file when sampling stops")
+	checkpointEvery = flag.Int("checkpoint-every", 0,
+		"Also write the checkpoint every this many samples")
+	resume = flag.String("resume", "",
+		"Resume from this checkpoint, sample_size counts the samples already taken")
+	deque = flag.String("deque", "unbounded",
+		"Worker deque of the parallel executors, (unbounded) or (chaselev)")
+	queueBound = flag.Int("queue", 0,
+		"Bound the global task queue, blocking task submission while full, 0 disables")
+	resizeTo = flag.Int("resize", 0,
+		"Resize the parallel executor to this many workers halfway through the samples, 0 disables")
+	steal = flag.String("steal", "batch",
+		"Steal policy of the work stealing executor, (one), (half), (batch) or (adaptive)")
+	stealBatch = flag.Int("steal-batch", 1000,
+		"Most tasks the batch and adaptive steal policies take at once, the batch policy only from larger victims")
+	victim = flag.String("victim", "random",
+		"Victim selection of the work stealing executor, (random), (roundrobin) or (last)")
+	treeFile = flag.String("tree", "",
+		"Export the tree to this .graphml, .dot or .json file")
+)
 
 func main() {
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
 
-	if len(os.Args) < 4 || len(os.Args) > 6 {
-		fmt.Println(usage)
+	if len(args) < 3 || len(args) > 5 {
+		flag.Usage()
+		return
+	}
+	if *trajectory != "" && (*maxVel <= 0 || *maxAcc <= 0 || *timeStep <= 0) {
+		fmt.Fprintln(os.Stderr, pathfind.ErrTrajectoryLimits)
 		return
 	}
 
 	// Parse command line arguments
 	var strategy string
 	threads := 1
-	mode := os.Args[1]
-	sample_size, _ := strconv.Atoi(os.Args[2])
-	input := os.Args[3]
-	if len(os.Args) == 6 {
-		strategy = os.Args[4]
-		threads, _ = strconv.Atoi(os.Args[5])
+	mode := args[0]
+	sample_size, _ := strconv.Atoi(args[1])
+	input := args[2]
+	if len(args) == 5 {
+		strategy = args[3]
+		threads, _ = strconv.Atoi(args[4])
 	}
 
 	// Run benchmark mode
-	var start time.Time
 	var end float64
-	if mode == "b" {
-		start = time.Now()
+	start := time.Now()
+
+	// Read the configuration space from the input file
+	configSpace, err := config.NewConfigSpace(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not read configuration: ", err)
+		return
 	}
+	configSpace.PruneEvery = int64(*prune)
+	if *resume != "" {
+		if err := Resume(configSpace, *resume); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not resume from checkpoint: ", err)
+			return
+		}
+	}
+
+	// Plan several robots in the same configuration space
+	if len(configSpace.Robots) > 0 {
+		spaces, conflicts, err := RunMultiAgent(configSpace, sample_size, threads, strategy)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not check conflicts: ", err)
+		}
+		if mode == "b" {
+			end = time.Since(start).Seconds()
+			fmt.Printf("%.2f\n", end)
+		} else if mode == "d" {
+			for i, space := range spaces {
+				fmt.Println("Robot", i, "distance after", sample_size,
+					"iterations: ", space.Path.Goal.Cost)
+			}
+			for _, c := range conflicts {
+				fmt.Println("Conflict between robots", c.RobotA, "and", c.RobotB,
+					"at time", c.Time, "near", c.Point.X, c.Point.Y)
+			}
+		}
+		for _, space := range spaces {
+			WriteReport(space, space.Path.GetPath(), start, mode)
+		}
+		return
+	}
+
+	// Record the convergence of the run and checkpoint it if requested
+	var trace *metrics.Trace
+	var recorders Recorders
+	if *traceFile != "" {
+		trace = metrics.NewTrace(*traceEvery, start)
+		recorders = append(recorders, trace)
+	}
+	var checkpointer *Checkpointer
+	if *checkpoint != "" {
+		checkpointer = &Checkpointer{File: *checkpoint, Every: *checkpointEvery}
+		recorders = append(recorders, checkpointer)
+	}
+	var recorder concurrent.Recorder
+	if len(recorders) > 0 {
+		recorder = recorders
+	}
+
+	// Stop early on the stopping criteria or Ctrl-C
+	stop := NewStopCriteria(*deadline, float32(*threshold), int64(*stall))
 
 	// Run the simulation
 	var pathOutput interface{}
+	var stats concurrent.Stats
 	if threads == 1 {
-		pathOutput = RunSequential(input, sample_size)
+		pathOutput = RunSequential(configSpace, sample_size, recorder, stop)
 	} else {
-		pathOutput = RunParallel(input, sample_size, threads, strategy)
+		pathOutput, stats = RunParallel(configSpace, sample_size, threads,
+			strategy, recorder, stop)
+	}
+	stop.Close()
+	if reason := stop.Reason(); reason != "" {
+		fmt.Fprintln(os.Stderr, "Stopped after", configSpace.Samples,
+			"samples: ", reason)
+	}
+	if checkpointer != nil {
+		if err := checkpointer.Write(configSpace); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not write checkpoint: ", err)
+		}
 	}
 
 	// Print run-time or draw the configuration space
+	path := configSpace.Path.GetPath()
 	if mode == "b" {
 		end = time.Since(start).Seconds()
 		fmt.Printf("%.2f\n", end)
+		// Executor telemetry goes to stderr to keep the timing parsable
+		if threads > 1 {
+			stats.WriteTable(os.Stderr)
+		}
 	} else if mode == "d" {
-		var dist float32
-		if threads == 1 {
-			out := pathOutput.([]float32)
+		dist := configSpace.Path.GetDistToGoal()
+		if out, ok := pathOutput.([]float32); ok && len(out) > 0 {
 			dist = out[len(out)-1]
-			fmt.Println("Distance after", sample_size, "iterations: ", dist)
-		} else {
-			out := pathOutput.([]concurrent.Future)
-			dist = out[len(out)-1].Get().(float32)
-			fmt.Println("Distance after", sample_size, "iterations: ", dist)
+		} else if out, ok := pathOutput.([]concurrent.Future); ok && len(out) > 0 {
+			// A task that panicked returns its error instead of a distance
+			if last, ok := out[len(out)-1].Get().(float32); ok {
+				dist = last
+			}
 		}
+		fmt.Println("Distance after", configSpace.Samples, "iterations: ", dist)
 		if dist == 0 {
 			fmt.Println("No Goal!")
 		} else {
 			fmt.Println("Goal!")
 		}
+		if path != nil {
+			processed := PostProcess(path, configSpace)
+			if *shortcut > 0 || *smooth > 0 {
+				fmt.Println("Path length before/after post-processing: ",
+					pathfind.PathLength(path), pathfind.PathLength(processed))
+			}
+			if *trajectory != "" {
+				traj, err := pathfind.NewTrajectory(processed,
+					float32(*maxVel), float32(*maxAcc), float32(*timeStep))
+				if err == nil {
+					err = traj.WriteCSV(*trajectory)
+				}
+				if err != nil {
+					fmt.Println("Could not write trajectory: ", err)
+				} else {
+					fmt.Println("Trajectory duration: ", traj.Duration())
+				}
+			}
+			path = processed
+		}
+	}
+	if *treeFile != "" {
+		if err := configSpace.Path.Graph().WriteFile(*treeFile); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not export tree: ", err)
+		}
+	}
+	if trace != nil {
+		if err := trace.WriteFile(*traceFile); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not write trace: ", err)
+		}
+	}
+	WriteReport(configSpace, path, start, mode)
+}
+
+// WriteReport prints the metrics of a run in the format given on the command
+// line. Benchmark mode prints to stderr to keep its timing output parsable.
+func WriteReport(configSpace *config.ConfigSpace,
+	path []*config.Point,
+	start time.Time,
+	mode string,
+) {
+	if *report == "none" {
+		return
+	}
+	out := os.Stdout
+	if mode == "b" {
+		out = os.Stderr
+	}
+	metrics.NewReport(configSpace, path, start).Write(out, *report)
+}
+
+// PostProcess shortcuts and then smooths a path according to the command line
+// options
+func PostProcess(path []*config.Point,
+	configSpace *config.ConfigSpace,
+) []*config.Point {
+	if *shortcut > 0 {
+		path = pathfind.Shortcut(path, configSpace, *shortcut)
+	}
+	if *smooth > 0 {
+		path = pathfind.SmoothPath(path, configSpace, *smooth)
 	}
+	return path
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"os/signal"
+	"pp_project/config"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// StopCriteria ends a run before all samples are taken. A run stops at a
+// deadline, once the best cost drops below a threshold, when the best cost
+// has not improved for a number of samples since a solution was found, or
+// on SIGINT or SIGTERM.
+type StopCriteria struct {
+	Deadline  time.Time          // Stop at this time, zero for no deadline
+	Threshold float32            // Stop once the best cost is below this, 0 disables
+	Stall     int64              // Stop after this many samples without improvement, 0 disables
+	reason    string             // Why the run stopped, empty while running
+	interrupt chan os.Signal     // Receives SIGINT and SIGTERM
+	ctx       context.Context    // Done once the run stopped
+	cancel    context.CancelFunc // Cancels ctx
+	best      uint32             // Best cost seen so far, float32 bits
+	bestAt    int64              // Sample count when the best cost was last improved
+	stopped   int32              // Set once the run stopped
+	lock      sync.Mutex         // Lock for the stop reason
+}
+
+// NewStopCriteria creates new StopCriteria and starts listening for SIGINT
+// and SIGTERM, which a batch scheduler sends before killing a job.
+// A zero timeout, threshold or stall disables that criterion.
+func NewStopCriteria(timeout time.Duration,
+	threshold float32,
+	stall int64,
+) *StopCriteria {
+	stop := &StopCriteria{
+		Threshold: threshold,
+		Stall:     stall,
+		interrupt: make(chan os.Signal, 1),
+	}
+	stop.ctx, stop.cancel = context.WithCancel(context.Background())
+	if timeout > 0 {
+		stop.Deadline = time.Now().Add(timeout)
+	}
+	signal.Notify(stop.interrupt, os.Interrupt, syscall.SIGTERM)
+	go stop.watch()
+	return stop
+}
+
+// Stops the run on a signal or at the deadline
+func (s *StopCriteria) watch() {
+	var deadline <-chan time.Time
+	if !s.Deadline.IsZero() {
+		timer := time.NewTimer(time.Until(s.Deadline))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	select {
+	case <-s.interrupt:
+		s.stopFor("interrupted")
+	case <-deadline:
+		s.stopFor("deadline reached")
+	case <-s.ctx.Done():
+	}
+}
+
+// Context returns a context that is done once the run stopped
+func (s *StopCriteria) Context() context.Context {
+	return s.ctx
+}
+
+// Done checks if the run on a config space should stop. It is called by every
+// task, so it only reads the criteria state unless the best cost improved.
+func (s *StopCriteria) Done(space *config.ConfigSpace) bool {
+	if atomic.LoadInt32(&s.stopped) == 1 {
+		return true
+	}
+
+	samples := atomic.LoadInt64(&space.Samples)
+	cost := space.Path.GetDistToGoal()
+	if cost != 0 {
+		s.improve(cost, samples)
+	}
+
+	if s.Threshold > 0 && cost != 0 && cost <= s.Threshold {
+		s.stopFor("cost threshold reached")
+	} else if s.Stall > 0 && atomic.LoadUint32(&s.best) != 0 &&
+		samples-atomic.LoadInt64(&s.bestAt) >= s.Stall {
+		s.stopFor("no improvement")
+	}
+	return atomic.LoadInt32(&s.stopped) == 1
+}
+
+// Records a cost seen after a number of samples if it improves the best cost
+func (s *StopCriteria) improve(cost float32, samples int64) {
+	for {
+		best := atomic.LoadUint32(&s.best)
+		if best != 0 && cost >= math.Float32frombits(best) {
+			return
+		}
+		if atomic.CompareAndSwapUint32(&s.best, best, math.Float32bits(cost)) {
+			break
+		}
+	}
+	// Concurrent improvements keep the latest sample count
+	for {
+		at := atomic.LoadInt64(&s.bestAt)
+		if at >= samples || atomic.CompareAndSwapInt64(&s.bestAt, at, samples) {
+			return
+		}
+	}
+}
+
+// Close stops listening for signals, a further signal ends the program
+func (s *StopCriteria) Close() {
+	signal.Stop(s.interrupt)
+	s.cancel()
+}
+
+// Reason returns why the run stopped, empty if it has not stopped
+func (s *StopCriteria) Reason() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.reason
+}
+
+// Stop the run for a given reason
+func (s *StopCriteria) stopFor(reason string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stop(reason)
+}
+
+// Stop the run for a given reason, with the lock held
+func (s *StopCriteria) stop(reason string) {
+	if s.reason == "" {
+		s.reason = reason
+	}
+	atomic.StoreInt32(&s.stopped, 1)
+	s.Close()
+}